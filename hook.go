@@ -0,0 +1,509 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/claude"
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/policy"
+	"coding-agent-dashboard/internal/process"
+	"coding-agent-dashboard/internal/state"
+)
+
+// hookContext carries everything a handler needs to process one hook invocation
+type hookContext struct {
+	stateManager *state.Manager
+	event        *claude.HookEvent
+	workingDir   string
+	decision     *policy.Decision // PreToolUse only: the matching policy rule, if any
+	server       *ipc.Conn        // Connection to the dashboard server, nil if it isn't running
+}
+
+// hookHandler applies a hook event to the agent status entry for its working directory
+type hookHandler func(ctx *hookContext, agentStatus *state.AgentStatus)
+
+// hookHandlers dispatches each Claude Code hook event to its handler
+var hookHandlers = map[string]hookHandler{
+	claude.HookEventPreToolUse:       handlePreToolUseEvent,
+	claude.HookEventPostToolUse:      handleToolUseEvent,
+	claude.HookEventNotification:     handleNotificationEvent,
+	claude.HookEventStop:             handleStopEvent,
+	claude.HookEventSubagentStop:     handleSubagentStopEvent,
+	claude.HookEventUserPromptSubmit: handleUserPromptSubmitEvent,
+	claude.HookEventPreCompact:       handlePreCompactEvent,
+	claude.HookEventSessionStart:     handleSessionStartEvent,
+	claude.HookEventSessionEnd:       handleSessionEndEvent,
+}
+
+func handleHookMode(stateManager *state.Manager) error {
+	// Read raw JSON data from stdin first for debugging
+	rawData, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	// Write raw input JSON to /tmp for debugging
+	timestamp := time.Now().Format("20060102-150405.000")
+	debugFile := fmt.Sprintf("/tmp/claude-hook-raw-%s.json", timestamp)
+	if err := os.WriteFile(debugFile, rawData, 0644); err != nil {
+		// Log error but continue - debugging shouldn't break functionality
+		log.Printf("Warning: failed to write debug file %s: %v", debugFile, err)
+	}
+
+	event, err := claude.ParseHookEvent(rawData)
+	if err != nil {
+		return err
+	}
+
+	// Prefer the cwd reported by Claude Code, falling back to our own working directory
+	workingDir := event.Cwd
+	if workingDir == "" {
+		workingDir, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	// Through a symlink, the same worktree would otherwise show up under two paths
+	workingDir = fsutil.CanonicalPath(workingDir)
+
+	handler, ok := hookHandlers[event.HookEventName]
+	if !ok {
+		// Unknown events still prove the agent is alive, so treat them as activity
+		log.Printf("Unhandled hook event %q, treating as running", event.HookEventName)
+		handler = handleToolUseEvent
+	}
+
+	ctx := &hookContext{
+		stateManager: stateManager,
+		event:        event,
+		workingDir:   workingDir,
+	}
+
+	// The status file stays the source of truth; the socket just tells the server right away
+	if conn, err := ipc.Dial(ipc.SocketPath(stateManager.ConfigDir())); err == nil {
+		ctx.server = conn
+		defer conn.Close()
+	}
+
+	// Policy decides tool calls before anything else so the status reflects the outcome
+	if event.HookEventName == claude.HookEventPreToolUse {
+		ctx.decision = evaluateToolPolicy(ctx)
+	}
+
+	agentStatus, err := updateHookStatus(ctx, func(agentStatus *state.AgentStatus) {
+		handler(ctx, agentStatus)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Debug: Log the hook data to a file to understand what we're receiving
+	logFile := "/tmp/claude-hook-debug.log"
+	if f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		defer f.Close()
+		debugMsg := fmt.Sprintf("[%s] Hook event: %s, SessionID: %s, Cwd: %s, ToolName: %s -> status: %s\n",
+			time.Now().Format("2006-01-02 15:04:05"), event.HookEventName, event.SessionID, workingDir, event.ToolName, agentStatus.Status)
+		f.WriteString(debugMsg)
+	}
+
+	// Stdout is reserved for hook output JSON, so report progress on stderr
+	fmt.Fprintf(os.Stderr, "Updated agent status: %s -> %s\n", workingDir, agentStatus.Status)
+	notifyServer(ctx)
+
+	if event.HookEventName == claude.HookEventPreToolUse {
+		return respondToToolUse(ctx)
+	}
+
+	return nil
+}
+
+// updateHookStatus applies a change to the status entry for the hook's session and saves it
+func updateHookStatus(ctx *hookContext, apply func(agentStatus *state.AgentStatus)) (*state.AgentStatus, error) {
+	var agentStatus state.AgentStatus
+	event := ctx.event
+	cause := state.TransitionCause{
+		Source:    state.CauseHook,
+		Event:     event.HookEventName,
+		SessionID: event.SessionID,
+		Path:      ctx.workingDir,
+	}
+
+	// The hook itself exits right away; the Claude Code process that ran it is what lives on
+	agent, err := process.FindAgent()
+	if err != nil {
+		log.Printf("Warning: failed to find Claude Code process: %v", err)
+	}
+
+	// Other hooks run concurrently, so the whole read-modify-write happens under the status lock
+	err = ctx.stateManager.UpdateAgentStatus(cause, func(statuses []state.AgentStatus) ([]state.AgentStatus, bool) {
+		// Start from the existing entry for this session so fields like the last prompt survive
+		key := (state.AgentStatus{Path: ctx.workingDir, SessionID: event.SessionID}).Key()
+		index := -1
+		for i, s := range statuses {
+			if s.Key() == key {
+				index = i
+				break
+			}
+		}
+
+		agentStatus = state.AgentStatus{SessionID: event.SessionID}
+		if index >= 0 {
+			agentStatus = statuses[index]
+		}
+		agentStatus.Path = ctx.workingDir
+		agentStatus.LastActivity = time.Now()
+		agentStatus.EndedAt = nil
+		agentStatus.ExitedAt = nil
+		agentStatus.ExitCode = nil
+		if agent != nil {
+			agentStatus.PID = agent.PID
+			agentStatus.PIDStartTime = agent.StartTime
+		}
+		if event.TranscriptPath != "" {
+			agentStatus.TranscriptPath = event.TranscriptPath
+		}
+		// Claude Code passes its environment on to hooks, so this identifies the wrapping minion
+		if minionPID, err := strconv.Atoi(os.Getenv(minionPIDEnv)); err == nil {
+			agentStatus.MinionPID = minionPID
+		}
+
+		apply(&agentStatus)
+
+		if index >= 0 {
+			statuses[index] = agentStatus
+		} else {
+			statuses = append(supersededStatusesRemoved(statuses, agentStatus), agentStatus)
+		}
+		return statuses, true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update agent status: %w", err)
+	}
+
+	return &agentStatus, nil
+}
+
+// supersededStatusesRemoved drops the entries a new session replaces: ended or exited
+// sessions and entries recorded before sessions were tracked for the same path. Other live
+// sessions in the worktree are kept.
+func supersededStatusesRemoved(statuses []state.AgentStatus, newStatus state.AgentStatus) []state.AgentStatus {
+	if newStatus.SessionID == "" {
+		return statuses
+	}
+
+	kept := statuses[:0]
+	for _, s := range statuses {
+		if s.Path == newStatus.Path && (s.SessionID == "" || s.Finished()) {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// notifyServer tells a connected dashboard server that the agent status changed
+func notifyServer(ctx *hookContext) {
+	if ctx.server == nil {
+		return
+	}
+
+	payload := ipc.HookEventPayload{Path: ctx.workingDir, Event: ctx.event.HookEventName}
+	if err := ctx.server.Send(ipc.TypeHookEvent, "", payload); err != nil {
+		log.Printf("Warning: failed to notify dashboard server: %v", err)
+	}
+}
+
+// evaluateToolPolicy checks a PreToolUse call against the repository and global policies
+func evaluateToolPolicy(ctx *hookContext) *policy.Decision {
+	configDir := ctx.stateManager.ConfigDir()
+	layers := make(map[string]*policy.Policy)
+
+	global, err := policy.Load(policy.GlobalPath(configDir))
+	if err != nil {
+		log.Printf("Warning: ignoring global policy: %v", err)
+	} else {
+		layers[policy.ScopeGlobal] = global
+	}
+
+	if repo := findRepositoryForPath(ctx.stateManager, ctx.workingDir); repo != nil {
+		repoPolicy, err := policy.Load(policy.RepositoryPath(configDir, repo.ID))
+		if err != nil {
+			log.Printf("Warning: ignoring policy for repository %s: %v", repo.Name, err)
+		} else {
+			layers[policy.ScopeRepository] = repoPolicy
+		}
+	}
+
+	req := policy.NewRequest(ctx.event.ToolName, ctx.event.ToolInput, ctx.workingDir)
+	return policy.Evaluate(req, layers)
+}
+
+// findRepositoryForPath returns the configured repository containing a canonical path
+func findRepositoryForPath(stateManager *state.Manager, path string) *state.Repository {
+	repos, err := stateManager.GetRepositories()
+	if err != nil {
+		return nil
+	}
+
+	var best *state.Repository
+	bestPath := ""
+	for i, repo := range repos {
+		// Repositories added by older versions may be stored through a symlink
+		repoPath := fsutil.CanonicalPath(repo.Path)
+		if path == repoPath || strings.HasPrefix(path, repoPath+"/") {
+			// Prefer the most specific repository when they are nested
+			if best == nil || len(repoPath) > len(bestPath) {
+				best = &repos[i]
+				bestPath = repoPath
+			}
+		}
+	}
+	return best
+}
+
+// needsDashboardApproval reports whether this PreToolUse call should block on the dashboard
+func needsDashboardApproval(ctx *hookContext) bool {
+	// Policy allow and deny are final; only unmatched calls and ask rules go to a human
+	if ctx.decision != nil && ctx.decision.Action != policy.ActionAsk {
+		return false
+	}
+
+	// Without a running server nobody can answer, so let Claude Code prompt as usual
+	return *approveMode && ctx.stateManager.IsServerRunning()
+}
+
+// respondToToolUse prints the permission decision for a PreToolUse call, if there is one
+func respondToToolUse(ctx *hookContext) error {
+	var decision, reason, rule, source string
+
+	switch {
+	case needsDashboardApproval(ctx):
+		approval, err := ctx.stateManager.AddToolApproval(ctx.workingDir, ctx.event.SessionID, ctx.event.ToolName, ctx.event.ToolInput)
+		if err != nil {
+			return fmt.Errorf("failed to request approval: %w", err)
+		}
+
+		decision, reason = waitForToolApproval(ctx, approval.ID)
+		source = "dashboard"
+		if ctx.decision != nil {
+			rule = ctx.decision.Rule
+		}
+
+		// Claude carries on after allow or deny; ask hands the prompt back to the terminal
+		if decision != claude.PermissionAsk {
+			if _, err := updateHookStatus(ctx, func(agentStatus *state.AgentStatus) {
+				agentStatus.Status = "running"
+				agentStatus.Activity = fmt.Sprintf("%s %s from dashboard", ctx.event.ToolName, approvalVerb(decision))
+			}); err != nil {
+				log.Printf("Warning: %v", err)
+			} else {
+				notifyServer(ctx)
+			}
+		}
+	case ctx.decision != nil:
+		decision, reason = ctx.decision.Action, ctx.decision.Reason
+		rule, source = ctx.decision.Rule, ctx.decision.Scope
+	default:
+		// Nothing to decide; Claude Code applies its own permission settings
+		return nil
+	}
+
+	req := policy.NewRequest(ctx.event.ToolName, ctx.event.ToolInput, ctx.workingDir)
+	target := req.Command
+	if target == "" && len(req.FilePaths) > 0 {
+		target = req.FilePaths[0]
+	}
+
+	if err := ctx.stateManager.RecordPolicyDecision(state.PolicyDecision{
+		Path:      ctx.workingDir,
+		SessionID: ctx.event.SessionID,
+		ToolName:  ctx.event.ToolName,
+		Target:    target,
+		Decision:  decision,
+		Reason:    reason,
+		Rule:      rule,
+		Source:    source,
+	}); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	output, err := json.Marshal(claude.NewPreToolUseOutput(decision, reason))
+	if err != nil {
+		return fmt.Errorf("failed to marshal hook output: %w", err)
+	}
+
+	fmt.Println(string(output))
+	return nil
+}
+
+// waitForToolApproval waits until an approval is decided or the timeout elapses. The
+// server wakes us over the socket when it can; the approval file is polled regardless.
+func waitForToolApproval(ctx *hookContext, approvalID string) (string, string) {
+	stateManager := ctx.stateManager
+	deadline := time.NewTimer(*approvalTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	if ctx.server != nil {
+		if err := ctx.server.Send(ipc.TypeWaitApproval, approvalID, ipc.ApprovalPayload{ID: approvalID}); err == nil {
+			go func() {
+				for {
+					msg, err := ctx.server.Receive()
+					if err != nil {
+						return
+					}
+					if msg.Type == ipc.TypeApprovalDecided {
+						select {
+						case wake <- struct{}{}:
+						default:
+						}
+					}
+				}
+			}()
+		}
+	}
+
+waitLoop:
+	for {
+		select {
+		case <-deadline.C:
+			break waitLoop
+		case <-ticker.C:
+		case <-wake:
+		}
+
+		approval, err := stateManager.GetToolApproval(approvalID)
+		if err != nil {
+			log.Printf("Failed to check approval %s: %v", approvalID, err)
+			continue
+		}
+
+		if approval.Status == state.ApprovalDecided {
+			return approval.Decision, approval.Reason
+		}
+	}
+
+	decision := *approvalTimeoutDecision
+	if !claude.IsPermissionDecision(decision) {
+		decision = claude.PermissionAsk
+	}
+	reason := fmt.Sprintf("No decision from the dashboard within %s", *approvalTimeout)
+
+	if _, err := stateManager.ExpireToolApproval(approvalID, decision, reason); err != nil {
+		// The dashboard may have decided just as we timed out; honour that decision
+		if approval, getErr := stateManager.GetToolApproval(approvalID); getErr == nil && approval.Status == state.ApprovalDecided {
+			return approval.Decision, approval.Reason
+		}
+		log.Printf("Failed to expire approval %s: %v", approvalID, err)
+	}
+
+	return decision, reason
+}
+
+// approvalVerb describes a decision for activity messages
+func approvalVerb(decision string) string {
+	if decision == claude.PermissionDeny {
+		return "denied"
+	}
+	return "approved"
+}
+
+// handlePreToolUseEvent marks the agent as running, or waiting when the call needs a decision
+func handlePreToolUseEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	switch {
+	case needsDashboardApproval(ctx):
+		agentStatus.Status = "waiting"
+		agentStatus.Activity = fmt.Sprintf("Awaiting approval for %s", ctx.event.ToolName)
+	case ctx.decision != nil && ctx.decision.Action == policy.ActionDeny:
+		agentStatus.Status = "running"
+		agentStatus.Activity = fmt.Sprintf("%s blocked by policy", ctx.event.ToolName)
+	default:
+		handleToolUseEvent(ctx, agentStatus)
+	}
+}
+
+// handleToolUseEvent marks the agent as running while it works with tools
+func handleToolUseEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	agentStatus.Status = "running"
+	if event.ToolName != "" {
+		agentStatus.Activity = fmt.Sprintf("Using %s", event.ToolName)
+	}
+}
+
+// handleNotificationEvent marks the agent as waiting; Claude Code only notifies
+// when it needs a permission decision or has been idle waiting for a prompt
+func handleNotificationEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	agentStatus.Status = "waiting"
+	if event.Message != "" {
+		agentStatus.Activity = event.Message
+	}
+}
+
+// handleStopEvent marks the agent idle once it has finished responding. This holds
+// with stop_hook_active set too: the turn has still ended, and if a Stop hook makes
+// Claude carry on, its next tool use marks it running again.
+func handleStopEvent(_ *hookContext, agentStatus *state.AgentStatus) {
+	agentStatus.Status = "idle"
+	agentStatus.Activity = "Finished responding"
+}
+
+// handleSubagentStopEvent records a finished subagent; the main agent carries on
+func handleSubagentStopEvent(_ *hookContext, agentStatus *state.AgentStatus) {
+	agentStatus.Status = "running"
+	agentStatus.Activity = "Subagent finished"
+}
+
+// handleUserPromptSubmitEvent records the prompt the user just submitted
+func handleUserPromptSubmitEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	agentStatus.Status = "running"
+	agentStatus.Activity = "Prompt submitted"
+	agentStatus.LastPrompt = event.Prompt
+}
+
+// handlePreCompactEvent marks the agent as compacting its context window
+func handlePreCompactEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	agentStatus.Status = "compacting"
+	agentStatus.Activity = "Compacting context"
+	if event.Trigger != "" {
+		agentStatus.Activity = fmt.Sprintf("Compacting context (%s)", event.Trigger)
+	}
+}
+
+// handleSessionStartEvent marks a fresh or resumed session as idle until a prompt arrives
+func handleSessionStartEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	agentStatus.Status = "idle"
+	agentStatus.Activity = "Session started"
+	if event.Source != "" {
+		agentStatus.Activity = fmt.Sprintf("Session started (%s)", event.Source)
+	}
+	if event.Source == "startup" || event.Source == "clear" {
+		agentStatus.LastPrompt = ""
+	}
+}
+
+// handleSessionEndEvent marks the session as ended so it no longer shows as idle
+func handleSessionEndEvent(ctx *hookContext, agentStatus *state.AgentStatus) {
+	event := ctx.event
+	now := time.Now()
+	agentStatus.Status = "ended"
+	agentStatus.EndedAt = &now
+	agentStatus.Activity = "Session ended"
+	if event.Reason != "" {
+		agentStatus.Activity = fmt.Sprintf("Session ended (%s)", event.Reason)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"coding-agent-dashboard/internal/claude"
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/state"
+)
+
+func TestFindRepositoryForPathThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	nested := filepath.Join(real, "nested")
+	if err := os.MkdirAll(filepath.Join(nested, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	stateManager, err := state.NewManager(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("failed to create state manager: %v", err)
+	}
+	defer stateManager.Close()
+
+	// Stored as added, through the symlink, as older versions did
+	err = stateManager.SaveRepositories([]state.Repository{
+		{ID: "repo_outer", Path: link, Name: "outer"},
+		{ID: "repo_nested", Path: filepath.Join(link, "nested"), Name: "nested"},
+	})
+	if err != nil {
+		t.Fatalf("failed to save repositories: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{fsutil.CanonicalPath(real), "repo_outer"},
+		{fsutil.CanonicalPath(filepath.Join(nested, "src")), "repo_nested"},
+		{fsutil.CanonicalPath(dir), ""},
+	}
+	for _, tt := range tests {
+		got := findRepositoryForPath(stateManager, tt.path)
+		if (got == nil && tt.want != "") || (got != nil && got.ID != tt.want) {
+			t.Errorf("findRepositoryForPath(%q) = %+v, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHandleStopEvent(t *testing.T) {
+	for _, stopHookActive := range []bool{false, true} {
+		ctx := &hookContext{event: &claude.HookEvent{HookEventName: "Stop", StopHookActive: stopHookActive}}
+		agentStatus := state.AgentStatus{Status: "running", Activity: "Using Bash"}
+
+		handleStopEvent(ctx, &agentStatus)
+		if agentStatus.Status != "idle" {
+			t.Errorf("Stop with stop_hook_active=%v left the agent %s, want idle", stopHookActive, agentStatus.Status)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/claude"
+	"coding-agent-dashboard/internal/state"
+)
+
+type ApprovalDecisionRequest struct {
+	Decision string `json:"decision"` // allow, deny or ask
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	approvals, err := s.stateManager.GetToolApprovals()
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get approvals: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(approvals)
+}
+
+func (s *Server) handleApprovalByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/approvals/")
+	if id == "" {
+		s.writeError(w, "Approval ID required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.requireJSON(w, r) {
+		return
+	}
+
+	var req ApprovalDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !claude.IsPermissionDecision(req.Decision) {
+		s.writeError(w, "Decision must be allow, deny or ask", http.StatusBadRequest)
+		return
+	}
+
+	if req.Reason == "" {
+		switch req.Decision {
+		case claude.PermissionAllow:
+			req.Reason = "Approved from the dashboard"
+		case claude.PermissionDeny:
+			req.Reason = "Denied from the dashboard"
+		case claude.PermissionAsk:
+			req.Reason = "Dashboard deferred the decision to the terminal"
+		}
+	}
+
+	approval, err := s.stateManager.ResolveToolApproval(id, req.Decision, req.Reason)
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			s.writeError(w, "Approval not found", http.StatusNotFound)
+		} else {
+			s.writeError(w, fmt.Sprintf("Failed to resolve approval: %v", err), http.StatusConflict)
+		}
+		return
+	}
+
+	s.notifyApprovalDecided(approval.ID)
+	s.stateManager.AddAction("approval", fmt.Sprintf("🛂 %s %s in %s", strings.ToUpper(req.Decision[:1])+req.Decision[1:], approval.ToolName, approval.Path))
+
+	json.NewEncoder(w).Encode(approval)
+}
+
+// broadcastApprovals pushes the current approval list to SSE clients
+func (s *Server) broadcastApprovals() {
+	approvals, err := s.stateManager.GetToolApprovals()
+	if err != nil {
+		log.Printf("Failed to get approvals for broadcast: %v", err)
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "approvals_update",
+		"data": approvals,
+	})
+}
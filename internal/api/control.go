@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"coding-agent-dashboard/internal/ipc"
+)
+
+// How long to wait for a minion to acknowledge a control action
+const minionControlTimeout = 5 * time.Second
+
+type MinionControlRequest struct {
+	Path     string `json:"path"`
+	MinionID string `json:"minion_id,omitempty"` // Defaults to the minion that connected last for Path
+	Action   string `json:"action"`              // escape, interrupt, terminate, kill or restart
+}
+
+var minionControlActions = map[string]bool{
+	ipc.ControlEscape:    true,
+	ipc.ControlInterrupt: true,
+	ipc.ControlTerminate: true,
+	ipc.ControlKill:      true,
+	ipc.ControlRestart:   true,
+}
+
+// handleMinionControl asks the minion running in a path to interrupt, stop or
+// restart its agent, and waits for it to acknowledge
+func (s *Server) handleMinionControl(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.requireJSON(w, r) {
+		return
+	}
+
+	var req MinionControlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" && req.MinionID == "" {
+		s.writeError(w, "Path or minion ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if !minionControlActions[req.Action] {
+		s.writeError(w, fmt.Sprintf("Unknown action %q", req.Action), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Web API: Sending %s to minion %s for path '%s'", req.Action, req.MinionID, req.Path)
+	ack, err := s.sendMinionControl(req.Path, req.MinionID, req.Action)
+	if err != nil {
+		s.writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !ack.OK {
+		s.writeError(w, fmt.Sprintf("Minion could not %s: %s", req.Action, ack.Error), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"status":    "acknowledged",
+		"path":      req.Path,
+		"minion_id": req.MinionID,
+		"action":    req.Action,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// sendMinionControl sends a control action to a minion, by ID or the latest for a
+// path, and waits for its acknowledgement
+func (s *Server) sendMinionControl(path, minionID, action string) (*ipc.ControlAckPayload, error) {
+	id := fmt.Sprintf("control_%d", time.Now().UnixNano())
+	acks := make(chan ipc.ControlAckPayload, 1)
+
+	conn := s.minionConn(path, minionID)
+	if conn == nil {
+		if minionID != "" {
+			return nil, fmt.Errorf("minion %s is not connected", minionID)
+		}
+		return nil, fmt.Errorf("no minion is connected for this path")
+	}
+
+	s.ipc.mutex.Lock()
+	s.ipc.controlWaiters[id] = acks
+	s.ipc.mutex.Unlock()
+
+	defer func() {
+		s.ipc.mutex.Lock()
+		delete(s.ipc.controlWaiters, id)
+		s.ipc.mutex.Unlock()
+	}()
+
+	if err := conn.Send(ipc.TypeMinionControl, id, ipc.ControlPayload{Action: action}); err != nil {
+		return nil, fmt.Errorf("failed to send %s to minion: %w", action, err)
+	}
+
+	select {
+	case ack := <-acks:
+		return &ack, nil
+	case <-time.After(minionControlTimeout):
+		return nil, fmt.Errorf("minion did not acknowledge %s within %v", action, minionControlTimeout)
+	}
+}
+
+// handleControlAck passes a minion's acknowledgement to the request waiting for it
+func (s *Server) handleControlAck(id string, ack ipc.ControlAckPayload) {
+	s.ipc.mutex.Lock()
+	acks := s.ipc.controlWaiters[id]
+	delete(s.ipc.controlWaiters, id)
+	s.ipc.mutex.Unlock()
+
+	if acks == nil {
+		log.Printf("IPC: ignoring acknowledgement for unknown control %s", id)
+		return
+	}
+	acks <- ack
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/git"
+)
+
+// handleDiff returns the changes in a worktree for review: uncommitted ones by
+// default, mode=merge-base for everything since the branch left the default branch
+// (or ?base=), or mode=commit&commit=<sha> for a single commit
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		s.writeError(w, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	if !s.isKnownWorktree(path) {
+		s.writeError(w, "Path is not a worktree of a registered repository", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := s.gitManager.GetDiff(path, git.DiffOptions{
+		Mode:   query.Get("mode"),
+		Base:   query.Get("base"),
+		Commit: query.Get("commit"),
+	})
+	if err != nil {
+		s.writeError(w, err.Error(), diffErrorStatus(err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(diff)
+}
+
+// diffErrorStatus picks the HTTP status for a diff that couldn't be made
+func diffErrorStatus(err error) int {
+	message := err.Error()
+	switch {
+	case errors.Is(err, git.ErrNotFound):
+		return http.StatusNotFound
+	case strings.Contains(message, "invalid"),
+		strings.Contains(message, "unknown"),
+		strings.Contains(message, "required"),
+		strings.Contains(message, "no commits"),
+		strings.Contains(message, "no merge-base"),
+		strings.Contains(message, "no default branch"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
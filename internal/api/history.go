@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// handleStatusHistory lists status transitions since ?since= (RFC3339, default start
+// of today), optionally for one agent with ?path=
+func (s *Server) handleStatusHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			s.writeError(w, "Invalid since time (use RFC3339)", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	// Statuses and transitions are recorded under canonical paths
+	path := r.URL.Query().Get("path")
+	if path != "" {
+		path = fsutil.CanonicalPath(path)
+	}
+
+	history, err := s.stateManager.GetStatusHistory(path, since)
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get status history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/state"
+)
+
+// How often queued minion messages are retried and expired, and held ones
+// checked against the agent's status
+const minionMessageRetryInterval = 5 * time.Second
+
+// ipcBroker tracks the hook and minion processes connected over the socket
+type ipcBroker struct {
+	server          *ipc.Server
+	minions         map[string]*connectedMinion           // minion ID -> connection
+	approvalWaiters map[string][]*ipc.Conn                // approval ID -> waiting hooks
+	terminals       map[string]*minionTerminal            // minion ID -> terminal output and viewers
+	controlWaiters  map[string]chan ipc.ControlAckPayload // control ID -> API request awaiting the ack
+	stop            chan struct{}
+	mutex           sync.Mutex
+}
+
+// connectedMinion is a minion subscribed over the socket
+type connectedMinion struct {
+	conn         *ipc.Conn
+	minion       state.Minion
+	subscribedAt time.Time
+}
+
+func newIPCBroker() *ipcBroker {
+	return &ipcBroker{
+		minions:         make(map[string]*connectedMinion),
+		approvalWaiters: make(map[string][]*ipc.Conn),
+		terminals:       make(map[string]*minionTerminal),
+		controlWaiters:  make(map[string]chan ipc.ControlAckPayload),
+		stop:            make(chan struct{}),
+	}
+}
+
+// StartIPC listens on the config dir socket so hooks and minions can reach the
+// server directly instead of going through the state files
+func (s *Server) StartIPC() error {
+	s.ipc.server = ipc.NewServer(ipc.SocketPath(s.stateManager.ConfigDir()), s)
+	if err := s.ipc.server.Start(); err != nil {
+		return err
+	}
+
+	s.stateManager.SetMinionMessageDeliverer(s.pushMinionMessage)
+	go s.retryMinionMessagesLoop()
+	return nil
+}
+
+// CloseIPC stops the socket server
+func (s *Server) CloseIPC() {
+	close(s.ipc.stop)
+	if s.ipc.server != nil {
+		s.ipc.server.Close()
+	}
+}
+
+// HandleMessage dispatches a message from a hook or minion process
+func (s *Server) HandleMessage(conn *ipc.Conn, msg *ipc.Message) {
+	switch msg.Type {
+	case ipc.TypeHookEvent:
+		var payload ipc.HookEventPayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		log.Printf("IPC: %s hook event for %s", payload.Event, payload.Path)
+		s.BroadcastStatusUpdate()
+		// The agent may have just become ready for its held messages
+		s.pushMinionMessagesForAgent(payload.Path)
+
+	case ipc.TypeSubscribe:
+		var payload ipc.SubscribePayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		s.subscribeMinion(conn, payload)
+
+	case ipc.TypeMessageReceipt:
+		var payload ipc.MessageReceiptPayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		log.Printf("IPC: message %s for %s is %s", payload.ID, payload.Path, payload.Status)
+		s.broadcastMinionMessages()
+		s.pushMinionMessage(payload.Path)
+
+	case ipc.TypeTerminalOutput:
+		var payload ipc.TerminalPayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		s.handleTerminalOutput(conn, payload)
+
+	case ipc.TypeControlAck:
+		var payload ipc.ControlAckPayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		s.handleControlAck(msg.ID, payload)
+
+	case ipc.TypeWaitApproval:
+		var payload ipc.ApprovalPayload
+		if err := msg.Decode(&payload); err != nil {
+			log.Printf("IPC: %v", err)
+			return
+		}
+		s.ipc.mutex.Lock()
+		s.ipc.approvalWaiters[payload.ID] = append(s.ipc.approvalWaiters[payload.ID], conn)
+		s.ipc.mutex.Unlock()
+
+		// The decision may have landed before the hook asked to wait
+		if approval, err := s.stateManager.GetToolApproval(payload.ID); err == nil && approval.Status != state.ApprovalPending {
+			s.notifyApprovalDecided(payload.ID)
+		}
+
+	default:
+		log.Printf("IPC: ignoring unknown message type %q", msg.Type)
+	}
+}
+
+// HandleDisconnect forgets a connection once its process goes away
+func (s *Server) HandleDisconnect(conn *ipc.Conn) {
+	s.ipc.mutex.Lock()
+	defer s.ipc.mutex.Unlock()
+
+	for id, subscriber := range s.ipc.minions {
+		if subscriber.conn == conn {
+			delete(s.ipc.minions, id)
+			s.closeTerminalLocked(id)
+			log.Printf("IPC: minion %s for %s disconnected", id, subscriber.minion.Path)
+		}
+	}
+
+	for id, waiters := range s.ipc.approvalWaiters {
+		remaining := waiters[:0]
+		for _, waiter := range waiters {
+			if waiter != conn {
+				remaining = append(remaining, waiter)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(s.ipc.approvalWaiters, id)
+		} else {
+			s.ipc.approvalWaiters[id] = remaining
+		}
+	}
+}
+
+// subscribeMinion registers a minion's connection and hands it messages queued while it was away
+func (s *Server) subscribeMinion(conn *ipc.Conn, payload ipc.SubscribePayload) {
+	// Minions from before IDs were handed out are told apart by path alone
+	id := payload.MinionID
+	if id == "" {
+		id = payload.Path
+	}
+
+	s.ipc.mutex.Lock()
+	s.ipc.minions[id] = &connectedMinion{
+		conn:         conn,
+		minion:       state.Minion{ID: id, PID: payload.PID, Path: payload.Path},
+		subscribedAt: time.Now(),
+	}
+	s.ipc.mutex.Unlock()
+	log.Printf("IPC: minion %s subscribed for %s", id, payload.Path)
+
+	s.pushMinionMessageTo(id)
+}
+
+// pushMinionMessage hands the next queued message to each minion subscribed for a path
+func (s *Server) pushMinionMessage(path string) {
+	s.ipc.mutex.Lock()
+	ids := s.minionIDsForPathLocked(path)
+	s.ipc.mutex.Unlock()
+
+	for _, id := range ids {
+		s.pushMinionMessageTo(id)
+	}
+}
+
+// pushMinionMessageTo hands the next queued message for a minion to it. The minion's
+// receipt triggers the push of the message after it.
+func (s *Server) pushMinionMessageTo(id string) {
+	s.ipc.mutex.Lock()
+	subscriber := s.ipc.minions[id]
+	s.ipc.mutex.Unlock()
+
+	if subscriber == nil {
+		return
+	}
+	path := subscriber.minion.Path
+
+	message, err := s.stateManager.ClaimMinionMessage(subscriber.minion)
+	if err != nil {
+		log.Printf("IPC: failed to read queued messages for %s: %v", path, err)
+		return
+	}
+	if message == nil {
+		return
+	}
+
+	if err := subscriber.conn.Send(ipc.TypeMinionMessage, message.ID, message); err != nil {
+		log.Printf("IPC: failed to deliver message to minion %s, leaving it queued: %v", id, err)
+		if err := s.stateManager.ReleaseMinionMessage(path, message.ID); err != nil {
+			log.Printf("IPC: failed to release message %s: %v", message.ID, err)
+		}
+	} else {
+		log.Printf("IPC: sent message %s to minion %s for %s", message.ID, id, path)
+	}
+	s.broadcastMinionMessages()
+}
+
+// pushMinionMessagesForAgent pushes messages to the minions whose directory contains
+// an agent's working directory
+func (s *Server) pushMinionMessagesForAgent(agentPath string) {
+	agentPath = filepath.Clean(agentPath)
+
+	s.ipc.mutex.Lock()
+	var ids []string
+	for id, subscriber := range s.ipc.minions {
+		clean := filepath.Clean(subscriber.minion.Path)
+		if agentPath == clean || strings.HasPrefix(agentPath, clean+string(filepath.Separator)) {
+			ids = append(ids, id)
+		}
+	}
+	s.ipc.mutex.Unlock()
+
+	for _, id := range ids {
+		s.pushMinionMessageTo(id)
+	}
+}
+
+// minionIDsForPathLocked returns the minions subscribed for a path, most recent
+// first. The caller must hold the IPC mutex.
+func (s *Server) minionIDsForPathLocked(path string) []string {
+	var subscribers []*connectedMinion
+	for _, subscriber := range s.ipc.minions {
+		if filepath.Clean(subscriber.minion.Path) == filepath.Clean(path) {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	sort.Slice(subscribers, func(i, j int) bool {
+		return subscribers[i].subscribedAt.After(subscribers[j].subscribedAt)
+	})
+
+	ids := make([]string, len(subscribers))
+	for i, subscriber := range subscribers {
+		ids[i] = subscriber.minion.ID
+	}
+	return ids
+}
+
+// minionConn returns the connection of a minion by ID, or of the minion that
+// subscribed most recently for a path when no ID is given
+func (s *Server) minionConn(path, minionID string) *ipc.Conn {
+	s.ipc.mutex.Lock()
+	defer s.ipc.mutex.Unlock()
+
+	if minionID == "" {
+		ids := s.minionIDsForPathLocked(path)
+		if len(ids) == 0 {
+			return nil
+		}
+		minionID = ids[0]
+	}
+
+	if subscriber := s.ipc.minions[minionID]; subscriber != nil {
+		return subscriber.conn
+	}
+	return nil
+}
+
+// retryMinionMessagesLoop periodically expires old messages and hands messages whose
+// receipt never came to their minion again
+func (s *Server) retryMinionMessagesLoop() {
+	ticker := time.NewTicker(minionMessageRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ipc.stop:
+			return
+		case <-ticker.C:
+			changed, err := s.stateManager.SweepMinionMessages()
+			if err != nil {
+				log.Printf("Failed to sweep minion messages: %v", err)
+			}
+			if changed {
+				s.broadcastMinionMessages()
+			}
+
+			s.ipc.mutex.Lock()
+			ids := make([]string, 0, len(s.ipc.minions))
+			for id := range s.ipc.minions {
+				ids = append(ids, id)
+			}
+			s.ipc.mutex.Unlock()
+
+			for _, id := range ids {
+				s.pushMinionMessageTo(id)
+			}
+		}
+	}
+}
+
+// notifyApprovalDecided wakes the hooks waiting on an approval
+func (s *Server) notifyApprovalDecided(id string) {
+	s.ipc.mutex.Lock()
+	waiters := s.ipc.approvalWaiters[id]
+	delete(s.ipc.approvalWaiters, id)
+	s.ipc.mutex.Unlock()
+
+	for _, conn := range waiters {
+		if err := conn.Send(ipc.TypeApprovalDecided, id, ipc.ApprovalPayload{ID: id}); err != nil {
+			log.Printf("IPC: failed to notify hook about approval %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/process"
+	"coding-agent-dashboard/internal/state"
+)
+
+// Command a launched minion runs when the request doesn't name one
+var defaultMinionCommand = []string{"claude"}
+
+type LaunchMinionRequest struct {
+	Path    string            `json:"path"`              // Worktree to run the agent in
+	Command []string          `json:"command,omitempty"` // Defaults to claude
+	Prompt  string            `json:"prompt,omitempty"`  // Initial prompt, passed as the last argument
+	Model   string            `json:"model,omitempty"`   // Passed as --model
+	Env     map[string]string `json:"env,omitempty"`     // Added to the server's environment
+	Record  bool              `json:"record,omitempty"`  // Record the terminal as an asciicast
+}
+
+// LaunchedMinion is a minion the server started and supervises
+type LaunchedMinion struct {
+	ID        string     `json:"id"` // Registry ID the minion takes, for targeting messages at it
+	PID       int        `json:"pid"`
+	Path      string     `json:"path"`
+	Command   []string   `json:"command"`
+	StartedAt time.Time  `json:"started_at"`
+	ExitedAt  *time.Time `json:"exited_at,omitempty"`
+	ExitCode  *int       `json:"exit_code,omitempty"`
+}
+
+func (s *Server) handleMinionLaunch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.getLaunchedMinions(w, r)
+	case "POST":
+		s.launchMinion(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) getLaunchedMinions(w http.ResponseWriter, r *http.Request) {
+	s.launchedMutex.Lock()
+	minions := make([]LaunchedMinion, 0, len(s.launched))
+	for _, minion := range s.launched {
+		minions = append(minions, *minion)
+	}
+	s.launchedMutex.Unlock()
+
+	sort.Slice(minions, func(i, j int) bool {
+		return minions[i].StartedAt.After(minions[j].StartedAt)
+	})
+	json.NewEncoder(w).Encode(minions)
+}
+
+// launchMinion starts a detached, headless minion running an agent in a worktree
+func (s *Server) launchMinion(w http.ResponseWriter, r *http.Request) {
+	if !s.requireJSON(w, r) {
+		return
+	}
+
+	var req LaunchMinionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Path == "" {
+		s.writeError(w, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	if !s.isKnownWorktree(req.Path) {
+		s.writeError(w, "Path is not a worktree of a registered repository", http.StatusBadRequest)
+		return
+	}
+
+	for key := range req.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			s.writeError(w, fmt.Sprintf("Invalid environment variable name %q", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	minion, err := s.startMinion(req)
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to launch minion: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(minion)
+}
+
+// isKnownWorktree reports whether path is a registered repository or one of its worktrees
+func (s *Server) isKnownWorktree(path string) bool {
+	repos, err := s.stateManager.GetRepositories()
+	if err != nil {
+		log.Printf("Failed to get repositories: %v", err)
+		return false
+	}
+
+	path = fsutil.CanonicalPath(path)
+	for _, repo := range repos {
+		if fsutil.CanonicalPath(repo.Path) == path {
+			return true
+		}
+		worktrees, err := s.gitManager.GetWorktrees(repo.Path)
+		if err != nil {
+			continue
+		}
+		for _, wt := range worktrees {
+			if fsutil.CanonicalPath(wt.Path) == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// startMinion runs this binary in headless minion mode and waits on it in the background
+func (s *Server) startMinion(req LaunchMinionRequest) (*LaunchedMinion, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dashboard executable: %w", err)
+	}
+
+	command := req.Command
+	if len(command) == 0 {
+		command = defaultMinionCommand
+	}
+	command = append([]string{}, command...)
+	if req.Model != "" {
+		command = append(command, "--model", req.Model)
+	}
+	if req.Prompt != "" {
+		command = append(command, req.Prompt)
+	}
+
+	args := []string{"--minion", "--headless"}
+	if req.Record {
+		args = append(args, "--record")
+	}
+	args = append(append(args, "--"), command...)
+
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = fsutil.CanonicalPath(req.Path)
+	cmd.Env = os.Environ()
+	for key, value := range req.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	id := state.NewMinionID()
+	cmd.Env = append(cmd.Env, state.MinionIDEnv+"="+id)
+	process.Detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start minion: %w", err)
+	}
+
+	minion := &LaunchedMinion{
+		ID:        id,
+		PID:       cmd.Process.Pid,
+		Path:      cmd.Dir,
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+
+	s.launchedMutex.Lock()
+	s.launched[minion.PID] = minion
+	s.launchedMutex.Unlock()
+
+	log.Printf("Launched minion %d in %s: %s", minion.PID, minion.Path, strings.Join(command, " "))
+	s.stateManager.AddAction("minion", fmt.Sprintf("🚀 Launched %s in %s", command[0], minion.Path))
+
+	go s.superviseMinion(cmd, minion)
+
+	result := *minion
+	return &result, nil
+}
+
+// superviseMinion reaps a launched minion and records how it exited
+func (s *Server) superviseMinion(cmd *exec.Cmd, minion *LaunchedMinion) {
+	err := cmd.Wait()
+
+	exitCode := process.ExitCode(err)
+	if exitCode == -1 {
+		log.Printf("Failed to wait for minion %d: %v", minion.PID, err)
+	}
+
+	now := time.Now()
+	s.launchedMutex.Lock()
+	minion.ExitedAt = &now
+	minion.ExitCode = &exitCode
+	s.launchedMutex.Unlock()
+
+	log.Printf("Minion %d in %s exited with code %d", minion.PID, minion.Path, exitCode)
+	s.stateManager.AddAction("minion", fmt.Sprintf("🛑 Minion in %s exited with code %d", minion.Path, exitCode))
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/state"
+)
+
+// handleMinionMessages lists minion messages in every state, newest first
+func (s *Server) handleMinionMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messages, err := s.stateManager.GetMinionMessages(r.URL.Query().Get("path"))
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get minion messages: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(newestMinionMessagesFirst(messages))
+}
+
+// handleMinionMessageByID returns a message's delivery state, retries it via
+// POST /api/minion/messages/{id}/retry, or stops holding it for the agent via
+// POST /api/minion/messages/{id}/send-now
+func (s *Server) handleMinionMessageByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/minion/messages/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		s.writeError(w, "Message ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && action == "":
+		message, err := s.stateManager.GetMinionMessage(id)
+		if err != nil {
+			s.writeError(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(message)
+
+	case r.Method == "POST" && action == "retry":
+		message, err := s.stateManager.RetryMinionMessage(id)
+		if err != nil {
+			if errors.Is(err, state.ErrNotFound) {
+				s.writeError(w, "Message not found", http.StatusNotFound)
+			} else {
+				s.writeError(w, fmt.Sprintf("Failed to retry message: %v", err), http.StatusConflict)
+			}
+			return
+		}
+		log.Printf("Web API: Retrying minion message %s for path '%s'", message.ID, message.Path)
+		s.broadcastMinionMessages()
+		json.NewEncoder(w).Encode(message)
+
+	case r.Method == "POST" && action == "send-now":
+		message, err := s.stateManager.SendMinionMessageNow(id)
+		if err != nil {
+			if errors.Is(err, state.ErrNotFound) {
+				s.writeError(w, "Message not found", http.StatusNotFound)
+			} else {
+				s.writeError(w, fmt.Sprintf("Failed to send message now: %v", err), http.StatusConflict)
+			}
+			return
+		}
+		log.Printf("Web API: Sending minion message %s for path '%s' now", message.ID, message.Path)
+		s.broadcastMinionMessages()
+		json.NewEncoder(w).Encode(message)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// broadcastMinionMessages pushes the current message list to SSE clients
+func (s *Server) broadcastMinionMessages() {
+	messages, err := s.stateManager.GetMinionMessages("")
+	if err != nil {
+		log.Printf("Failed to get minion messages for broadcast: %v", err)
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "messages_update",
+		"data": newestMinionMessagesFirst(messages),
+	})
+}
+
+// newestMinionMessagesFirst reverses the store's oldest-first order in place
+func newestMinionMessagesFirst(messages []state.MinionMessage) []state.MinionMessage {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/state"
+)
+
+// MinionInfo is a registered minion and whether it is connected to this server
+type MinionInfo struct {
+	state.Minion
+	Connected bool `json:"connected"`
+}
+
+// handleMinions lists running minions, oldest first, optionally limited to ?path=
+func (s *Server) handleMinions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	minions, err := s.stateManager.GetMinions(r.URL.Query().Get("path"))
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get minions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	infos := make([]MinionInfo, 0, len(minions))
+	for _, minion := range minions {
+		infos = append(infos, s.minionInfo(minion))
+	}
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleMinionByID returns a single running minion
+func (s *Server) handleMinionByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/minions/")
+	if id == "" {
+		s.writeError(w, "Minion ID required", http.StatusBadRequest)
+		return
+	}
+
+	minion, err := s.stateManager.GetMinion(id)
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			s.writeError(w, "Minion not found", http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get minion %s: %v", id, err)
+			s.writeError(w, fmt.Sprintf("Failed to get minion: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.minionInfo(*minion))
+}
+
+func (s *Server) minionInfo(minion state.Minion) MinionInfo {
+	s.ipc.mutex.Lock()
+	_, connected := s.ipc.minions[minion.ID]
+	s.ipc.mutex.Unlock()
+
+	return MinionInfo{Minion: minion, Connected: connected}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"coding-agent-dashboard/internal/policy"
+)
+
+// handlePolicy reads or replaces the global policy, or a repository's policy
+// when ?repository=<id> is given
+func (s *Server) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	policyPath := policy.GlobalPath(s.stateManager.ConfigDir())
+	if repoID := r.URL.Query().Get("repository"); repoID != "" {
+		if !s.repositoryExists(repoID) {
+			s.writeError(w, "Repository not found", http.StatusNotFound)
+			return
+		}
+		policyPath = policy.RepositoryPath(s.stateManager.ConfigDir(), repoID)
+	}
+
+	switch r.Method {
+	case "GET":
+		p, err := policy.Load(policyPath)
+		if err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to load policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	case "PUT":
+		var p policy.Policy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := p.Validate(); err != nil {
+			s.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := policy.Save(policyPath, &p); err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to save policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		s.stateManager.AddAction("policy", fmt.Sprintf("📜 Updated policy (%d rules)", len(p.Rules)))
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePolicyDecisions lists recent policy and approval decisions, optionally for one path
+func (s *Server) handlePolicyDecisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	decisions, err := s.stateManager.GetPolicyDecisions(r.URL.Query().Get("path"))
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get policy decisions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(decisions)
+}
+
+// repositoryExists reports whether a repository ID is configured
+func (s *Server) repositoryExists(id string) bool {
+	repos, err := s.stateManager.GetRepositories()
+	if err != nil {
+		return false
+	}
+
+	for _, repo := range repos {
+		if repo.ID == id {
+			return true
+		}
+	}
+	return false
+}
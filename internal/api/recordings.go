@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleRecordings lists minion terminal recordings, optionally for one path
+func (s *Server) handleRecordings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	recordings, err := s.stateManager.GetRecordings(r.URL.Query().Get("path"))
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to get recordings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(recordings)
+}
+
+// handleRecordingByID serves a recording's asciicast file for playback. Recordings
+// still in progress are served up to their current length.
+func (s *Server) handleRecordingByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/recordings/")
+	if id == "" {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeError(w, "Recording ID required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, recording, err := s.stateManager.OpenRecording(id)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	http.ServeContent(w, r, recording.ID+".cast", recording.UpdatedAt, file)
+}
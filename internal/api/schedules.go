@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/state"
+)
+
+// ScheduleRequest creates a scheduled or recurring minion message
+type ScheduleRequest struct {
+	Path        string     `json:"path"`
+	MinionID    string     `json:"minion_id,omitempty"`
+	Message     string     `json:"message"`
+	Kind        string     `json:"kind"`                   // "at", "idle" or "cron"
+	At          *time.Time `json:"at,omitempty"`           // RFC 3339 time for "at"
+	IdleMinutes int        `json:"idle_minutes,omitempty"` // Idle time for "idle"
+	Cron        string     `json:"cron,omitempty"`         // Cron expression for "cron"
+	TTLSeconds  int        `json:"ttl_seconds,omitempty"`  // How long each message may wait for delivery
+}
+
+// ScheduleUpdateRequest pauses or resumes a schedule
+type ScheduleUpdateRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleSchedules lists schedules, optionally limited to ?path=, or creates one
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		path := r.URL.Query().Get("path")
+		if path != "" {
+			path = fsutil.CanonicalPath(path)
+		}
+		schedules, err := s.stateManager.GetSchedules(path)
+		if err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to get schedules: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(schedules)
+
+	case "POST":
+		if !s.requireJSON(w, r) {
+			return
+		}
+
+		var req ScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		schedule := state.MessageSchedule{
+			Path:        req.Path,
+			MinionID:    req.MinionID,
+			Message:     req.Message,
+			Kind:        req.Kind,
+			At:          req.At,
+			IdleMinutes: req.IdleMinutes,
+			Cron:        req.Cron,
+			TTLSeconds:  req.TTLSeconds,
+		}
+		if schedule.Path != "" {
+			schedule.Path = fsutil.CanonicalPath(schedule.Path)
+		}
+		if req.MinionID != "" {
+			minion, err := s.stateManager.GetMinion(req.MinionID)
+			if err != nil {
+				s.writeError(w, "Minion not found", http.StatusNotFound)
+				return
+			}
+			if schedule.Path != "" && schedule.Path != fsutil.CanonicalPath(minion.Path) {
+				s.writeError(w, "Minion is not running in this path", http.StatusBadRequest)
+				return
+			}
+			schedule.Path = minion.Path
+		}
+
+		created, err := s.stateManager.AddSchedule(schedule)
+		if err != nil {
+			s.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Web API: Scheduled %s message %s for path '%s'", created.Kind, created.ID, created.Path)
+		s.broadcastSchedules()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleScheduleByID returns, pauses or resumes, or deletes a single schedule
+func (s *Server) handleScheduleByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/schedules/")
+	if id == "" {
+		s.writeError(w, "Schedule ID required", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		schedule *state.MessageSchedule
+		err      error
+	)
+	switch r.Method {
+	case "GET":
+		schedule, err = s.stateManager.GetSchedule(id)
+
+	case "POST":
+		if !s.requireJSON(w, r) {
+			return
+		}
+
+		var req ScheduleUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		schedule, err = s.stateManager.SetScheduleEnabled(id, req.Enabled)
+		if err == nil {
+			log.Printf("Web API: Set schedule %s enabled=%v", id, req.Enabled)
+			s.broadcastSchedules()
+		}
+
+	case "DELETE":
+		err = s.stateManager.DeleteSchedule(id)
+		if err == nil {
+			log.Printf("Web API: Deleted schedule %s", id)
+			s.broadcastSchedules()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			s.writeError(w, "Schedule not found", http.StatusNotFound)
+		} else {
+			log.Printf("Failed to handle schedule %s: %v", id, err)
+			s.writeError(w, fmt.Sprintf("Failed to handle schedule: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// broadcastSchedules pushes the current schedule list to SSE clients
+func (s *Server) broadcastSchedules() {
+	schedules, err := s.stateManager.GetSchedules("")
+	if err != nil {
+		log.Printf("Failed to get schedules for broadcast: %v", err)
+		return
+	}
+
+	s.hub.Broadcast(map[string]interface{}{
+		"type": "schedules_update",
+		"data": schedules,
+	})
+}
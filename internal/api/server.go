@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"os/exec"
@@ -11,7 +13,10 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"coding-agent-dashboard/internal/claude"
+	"coding-agent-dashboard/internal/fsutil"
 	"coding-agent-dashboard/internal/git"
 	"coding-agent-dashboard/internal/state"
 )
@@ -25,6 +30,10 @@ type Server struct {
 	stateManager *state.Manager
 	gitManager   *git.Manager
 	hub          *SSEHub
+	ipc          *ipcBroker
+
+	launched      map[int]*LaunchedMinion // PID -> minion started from the dashboard
+	launchedMutex sync.Mutex
 }
 
 type AddRepositoryRequest struct {
@@ -33,8 +42,12 @@ type AddRepositoryRequest struct {
 }
 
 type MinionMessageRequest struct {
-	Path    string `json:"path"`
-	Message string `json:"message"`
+	Path       string `json:"path"`                // Any minion in this directory takes it
+	MinionID   string `json:"minion_id,omitempty"` // Only this minion takes it; Path may be left out
+	Broadcast  bool   `json:"broadcast,omitempty"` // Every minion in Path gets its own copy
+	Message    string `json:"message"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"` // How long the message may wait for delivery
+	SendNow    bool   `json:"send_now,omitempty"`    // Don't wait for the agent to finish responding
 }
 
 type ErrorResponse struct {
@@ -88,6 +101,8 @@ func NewServer(stateManager *state.Manager, gitManager *git.Manager) *Server {
 		stateManager: stateManager,
 		gitManager:   gitManager,
 		hub:          NewSSEHub(),
+		ipc:          newIPCBroker(),
+		launched:     make(map[int]*LaunchedMinion),
 	}
 }
 
@@ -100,14 +115,32 @@ func (s *Server) Start(port string) error {
 	http.HandleFunc("/api/repositories", s.handleRepositories)
 	http.HandleFunc("/api/repositories/", s.handleRepositoryByID)
 	http.HandleFunc("/api/status", s.handleStatus)
+	http.HandleFunc("/api/status/history", s.handleStatusHistory)
 	http.HandleFunc("/api/webhook/claude", s.handleClaudeWebhook)
 	http.HandleFunc("/api/actions/open-ide", s.handleOpenIDE)
 	http.HandleFunc("/api/binary-path", s.handleBinaryPath)
 	http.HandleFunc("/api/suggestions/directories", s.handleDirectorySuggestions)
 	http.HandleFunc("/api/hooks/status", s.handleHookStatus)
 	http.HandleFunc("/api/hooks/install", s.handleHookInstall)
+	http.HandleFunc("/api/minions", s.handleMinions)
+	http.HandleFunc("/api/minions/", s.handleMinionByID)
 	http.HandleFunc("/api/minion/message", s.handleMinionMessage)
+	http.HandleFunc("/api/minion/messages", s.handleMinionMessages)
+	http.HandleFunc("/api/minion/messages/", s.handleMinionMessageByID)
+	http.HandleFunc("/api/minion/terminal", s.handleMinionTerminal)
+	http.HandleFunc("/api/minion/control", s.handleMinionControl)
+	http.HandleFunc("/api/minion/launch", s.handleMinionLaunch)
+	http.HandleFunc("/api/tasks", s.handleTasks)
+	http.HandleFunc("/api/diff", s.handleDiff)
+	http.HandleFunc("/api/schedules", s.handleSchedules)
+	http.HandleFunc("/api/schedules/", s.handleScheduleByID)
+	http.HandleFunc("/api/recordings", s.handleRecordings)
+	http.HandleFunc("/api/recordings/", s.handleRecordingByID)
 	http.HandleFunc("/api/system-commands", s.handleSystemCommands)
+	http.HandleFunc("/api/approvals", s.handleApprovals)
+	http.HandleFunc("/api/approvals/", s.handleApprovalByID)
+	http.HandleFunc("/api/policy", s.handlePolicy)
+	http.HandleFunc("/api/policy/decisions", s.handlePolicyDecisions)
 	http.HandleFunc("/events", s.handleSSE)
 
 	fmt.Printf("Serving at http://localhost:%s\n", port)
@@ -137,6 +170,11 @@ func (s *Server) handleRepositoryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if id, ok := strings.CutSuffix(path, "/worktrees"); ok {
+		s.handleWorktrees(w, r, id)
+		return
+	}
+
 	switch r.Method {
 	case "DELETE":
 		s.removeRepository(w, r, path)
@@ -162,20 +200,25 @@ func (s *Server) getRepositories(w http.ResponseWriter, r *http.Request) {
 			// If we can't get worktrees, still include the repo but with empty worktrees
 			worktrees = []state.Worktree{}
 		}
+		s.addWorktreeStatuses(repo.Path, worktrees)
 
-		// Find relevant status entries
+		// Find relevant status entries, comparing canonical paths so a repository
+		// added through a symlink still matches the paths hooks report
+		repoPath := fsutil.CanonicalPath(repo.Path)
 		var repoStatuses []state.AgentStatusWithMessages
 		for _, status := range agentStatuses {
 			// Check if status path is within this repo or any of its worktrees
 			isRelevant := false
+			statusPath := fsutil.CanonicalPath(status.Path)
 			
 			// Check if status path is within the main repository
-			if strings.HasPrefix(status.Path, repo.Path+"/") || status.Path == repo.Path {
+			if strings.HasPrefix(statusPath, repoPath+"/") || statusPath == repoPath {
 				isRelevant = true
 			} else {
 				// Check if status path is within any worktree
 				for _, wt := range worktrees {
-					if strings.HasPrefix(status.Path, wt.Path+"/") || status.Path == wt.Path {
+					wtPath := fsutil.CanonicalPath(wt.Path)
+					if strings.HasPrefix(statusPath, wtPath+"/") || statusPath == wtPath {
 						isRelevant = true
 						break
 					}
@@ -216,14 +259,30 @@ func (s *Server) addRepository(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A linked worktree is added as the repository it belongs to, which brings
+	// in all of its worktrees
+	info, err := s.gitManager.Inspect(req.Path)
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Failed to inspect repository: %v", err), http.StatusBadRequest)
+		return
+	}
+	if info.LinkedWorktree {
+		log.Printf("Web API: %s is a worktree of %s, adding that instead", req.Path, info.Root)
+	}
+	req.Path = info.Root
+
 	// Generate name from path if not provided
 	if req.Name == "" {
-		req.Name = filepath.Base(req.Path)
+		req.Name = strings.TrimSuffix(filepath.Base(req.Path), ".git")
 	}
 
 	repo, err := s.stateManager.AddRepository(req.Path, req.Name)
 	if err != nil {
-		s.writeError(w, fmt.Sprintf("Failed to add repository: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, state.ErrExists) {
+			s.writeError(w, fmt.Sprintf("Failed to add repository: %v", err), http.StatusConflict)
+		} else {
+			s.writeError(w, fmt.Sprintf("Failed to add repository: %v", err), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -233,7 +292,7 @@ func (s *Server) addRepository(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) removeRepository(w http.ResponseWriter, r *http.Request, id string) {
 	if err := s.stateManager.RemoveRepository(id); err != nil {
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, state.ErrNotFound) {
 			s.writeError(w, "Repository not found", http.StatusNotFound)
 		} else {
 			s.writeError(w, fmt.Sprintf("Failed to remove repository: %v", err), http.StatusInternalServerError)
@@ -371,7 +430,7 @@ func (s *Server) getDirectorySuggestions(query string) []DirectorySuggestion {
 					Path:      path,
 					Name:      filepath.Base(path),
 					Type:      "common",
-					IsGitRepo: s.gitManager.IsGitRepository(path),
+					IsGitRepo: s.gitManager.LooksLikeGitRepository(path),
 				}
 				suggestion.HasGitRepos = s.hasGitRepositories(path)
 				suggestions = append(suggestions, suggestion)
@@ -445,7 +504,7 @@ func (s *Server) suggestFromPath(query string) []DirectorySuggestion {
 			Path:      fullPath,
 			Name:      entry.Name(),
 			Type:      "directory",
-			IsGitRepo: s.gitManager.IsGitRepository(fullPath),
+			IsGitRepo: s.gitManager.LooksLikeGitRepository(fullPath),
 		}
 		suggestion.HasGitRepos = s.hasGitRepositories(fullPath)
 
@@ -467,7 +526,7 @@ func (s *Server) hasGitRepositories(dir string) bool {
 		}
 
 		fullPath := filepath.Join(dir, entry.Name())
-		if s.gitManager.IsGitRepository(fullPath) {
+		if s.gitManager.LooksLikeGitRepository(fullPath) {
 			return true
 		}
 	}
@@ -502,7 +561,8 @@ func (s *Server) handleHookInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Path string `json:"path"`
+		Path     string `json:"path"`
+		Approval bool   `json:"approval,omitempty"` // Block PreToolUse until the dashboard decides
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -515,7 +575,7 @@ func (s *Server) handleHookInstall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.installHook(req.Path); err != nil {
+	if err := s.installHook(req.Path, req.Approval); err != nil {
 		s.writeError(w, fmt.Sprintf("Failed to install hook: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -529,10 +589,11 @@ func (s *Server) handleHookInstall(w http.ResponseWriter, r *http.Request) {
 }
 
 type HookStatus struct {
-	Path         string `json:"path"`
-	IsInstalled  bool   `json:"is_installed"`
-	ConfigPath   string `json:"config_path"`
-	HasGitIgnore bool   `json:"has_gitignore"`
+	Path            string `json:"path"`
+	IsInstalled     bool   `json:"is_installed"`
+	ApprovalEnabled bool   `json:"approval_enabled"`
+	ConfigPath      string `json:"config_path"`
+	HasGitIgnore    bool   `json:"has_gitignore"`
 }
 
 func (s *Server) checkHookStatus(repoPath string) HookStatus {
@@ -540,15 +601,17 @@ func (s *Server) checkHookStatus(repoPath string) HookStatus {
 	gitignorePath := filepath.Join(repoPath, ".gitignore")
 
 	isInstalled := s.hasHooksInConfig(configPath)
+	approvalEnabled := s.hasApprovalHookInConfig(configPath)
 
 	_, err := os.Stat(gitignorePath)
 	hasGitIgnore := err == nil
 
 	return HookStatus{
-		Path:         repoPath,
-		IsInstalled:  isInstalled,
-		ConfigPath:   configPath,
-		HasGitIgnore: hasGitIgnore,
+		Path:            repoPath,
+		IsInstalled:     isInstalled,
+		ApprovalEnabled: approvalEnabled,
+		ConfigPath:      configPath,
+		HasGitIgnore:    hasGitIgnore,
 	}
 }
 
@@ -573,17 +636,46 @@ func (s *Server) hasHooksInConfig(configPath string) bool {
 		return false
 	}
 
-	// Check if any of our expected hooks exist
-	for _, event := range []string{"PreToolUse", "PostToolUse", "Stop", "Notification"} {
-		if _, exists := hooksMap[event]; exists {
-			return true
+	// Require every event we handle so older installs are offered a reinstall
+	for _, event := range claude.HookEvents {
+		if _, exists := hooksMap[event]; !exists {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasApprovalHookInConfig reports whether the installed PreToolUse hook blocks for dashboard approval
+func (s *Server) hasApprovalHookInConfig(configPath string) bool {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return false
+	}
+
+	var config struct {
+		Hooks map[string][]struct {
+			Hooks []struct {
+				Command string `json:"command"`
+			} `json:"hooks"`
+		} `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return false
+	}
+
+	for _, matcher := range config.Hooks[claude.HookEventPreToolUse] {
+		for _, hook := range matcher.Hooks {
+			if strings.Contains(hook.Command, "--approve") {
+				return true
+			}
 		}
 	}
 
 	return false
 }
 
-func (s *Server) installHook(repoPath string) error {
+func (s *Server) installHook(repoPath string, approval bool) error {
 	fmt.Printf("Installing hook for repository: %s\n", repoPath)
 
 	// Validate that the repository path exists
@@ -614,12 +706,20 @@ func (s *Server) installHook(repoPath string) error {
 	configPath := filepath.Join(claudeDir, "settings.local.json")
 	fmt.Printf("Updating hook config at: %s\n", configPath)
 
-	if err := s.mergeHookConfig(configPath); err != nil {
+	if err := s.mergeHookConfig(configPath, approval); err != nil {
 		return fmt.Errorf("failed to merge hook config: %w", err)
 	}
 
-	// Update .gitignore to exclude .claude
+	// Update .gitignore to exclude .claude. A linked worktree, such as a task's, gets
+	// the entry in the repository's info/exclude instead, so it starts out clean and
+	// can be removed later without forcing.
 	gitignorePath := filepath.Join(repoPath, ".gitignore")
+	if info, err := s.gitManager.Inspect(repoPath); err == nil && info.LinkedWorktree {
+		gitignorePath = filepath.Join(info.CommonDir, "info", "exclude")
+		if err := os.MkdirAll(filepath.Dir(gitignorePath), 0755); err != nil {
+			return fmt.Errorf("failed to create info directory: %w", err)
+		}
+	}
 	fmt.Printf("Updating .gitignore at: %s\n", gitignorePath)
 	if err := s.updateGitIgnore(gitignorePath); err != nil {
 		return fmt.Errorf("failed to update .gitignore: %w", err)
@@ -633,7 +733,7 @@ func (s *Server) installHook(repoPath string) error {
 	return nil
 }
 
-func (s *Server) mergeHookConfig(configPath string) error {
+func (s *Server) mergeHookConfig(configPath string, approval bool) error {
 	// Check if file exists to determine operation type
 	operation := "create"
 	if _, err := os.Stat(configPath); err == nil {
@@ -652,7 +752,7 @@ func (s *Server) mergeHookConfig(configPath string) error {
 	}
 
 	// Add hooks to the configuration
-	hookConfig := s.generateHookConfig()
+	hookConfig := s.generateHookConfig(approval)
 	config["hooks"] = hookConfig["hooks"]
 
 	// Write merged configuration
@@ -676,7 +776,11 @@ func (s *Server) mergeHookConfig(configPath string) error {
 	return nil
 }
 
-func (s *Server) generateHookConfig() map[string]interface{} {
+// approvalHookTimeout is the Claude Code hook timeout, in seconds, for blocking
+// approval hooks; it must outlast the hook's own --approval-timeout default
+const approvalHookTimeout = 330
+
+func (s *Server) generateHookConfig(approval bool) map[string]interface{} {
 	// Get the full path to the current executable
 	execPath, err := os.Executable()
 	if err != nil {
@@ -686,53 +790,28 @@ func (s *Server) generateHookConfig() map[string]interface{} {
 
 	command := fmt.Sprintf("%s --hook", execPath)
 
-	return map[string]interface{}{
-		"hooks": map[string]interface{}{
-			"PreToolUse": []map[string]interface{}{
-				{
-					"matcher": "*",
-					"hooks": []map[string]interface{}{
-						{
-							"type":    "command",
-							"command": command,
-						},
-					},
-				},
-			},
-			"PostToolUse": []map[string]interface{}{
-				{
-					"matcher": "*",
-					"hooks": []map[string]interface{}{
-						{
-							"type":    "command",
-							"command": command,
-						},
-					},
-				},
-			},
-			"Stop": []map[string]interface{}{
-				{
-					"matcher": "*",
-					"hooks": []map[string]interface{}{
-						{
-							"type":    "command",
-							"command": command,
-						},
-					},
-				},
-			},
-			"Notification": []map[string]interface{}{
-				{
-					"matcher": "*",
-					"hooks": []map[string]interface{}{
-						{
-							"type":    "command",
-							"command": command,
-						},
-					},
-				},
+	// Register the same command for every event; hook mode dispatches on hook_event_name
+	hooks := make(map[string]interface{})
+	for _, event := range claude.HookEvents {
+		hook := map[string]interface{}{
+			"type":    "command",
+			"command": command,
+		}
+		if approval && event == claude.HookEventPreToolUse {
+			hook["command"] = command + " --approve"
+			hook["timeout"] = approvalHookTimeout
+		}
+
+		hooks[event] = []map[string]interface{}{
+			{
+				"matcher": "*",
+				"hooks":   []map[string]interface{}{hook},
 			},
-		},
+		}
+	}
+
+	return map[string]interface{}{
+		"hooks": hooks,
 	}
 }
 
@@ -822,6 +901,9 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 		s.hub.Broadcast(message)
 	}
 
+	// Send initial approvals
+	s.broadcastApprovals()
+
 	// Stream events to client
 	for {
 		select {
@@ -869,6 +951,8 @@ func (s *Server) BroadcastStatusUpdate() {
 		}
 		s.hub.Broadcast(actionMessage)
 	}
+	s.broadcastApprovals()
+	s.broadcastSchedules()
 }
 
 func (s *Server) writeError(w http.ResponseWriter, message string, status int) {
@@ -876,6 +960,17 @@ func (s *Server) writeError(w http.ResponseWriter, message string, status int) {
 	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
 }
 
+// requireJSON rejects a request whose body isn't JSON, reporting whether it may go
+// on. Cross-site forms can't send JSON, so state-changing requests that require it
+// can't be made from other pages.
+func (s *Server) requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		s.writeError(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
+
 func (s *Server) openPyCharmLinux(projectPath string) error {
 	// Common PyCharm command names on Linux
 	commands := []string{
@@ -945,14 +1040,23 @@ func (s *Server) handleMinionMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.requireJSON(w, r) {
+		return
+	}
+
 	var req MinionMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeError(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	if req.Path == "" {
-		s.writeError(w, "Path is required", http.StatusBadRequest)
+	if req.Path == "" && req.MinionID == "" {
+		s.writeError(w, "Path or minion ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.MinionID != "" && req.Broadcast {
+		s.writeError(w, "A message can't both target a minion and be broadcast", http.StatusBadRequest)
 		return
 	}
 
@@ -961,21 +1065,65 @@ func (s *Server) handleMinionMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Add message to the minion queue for this path
-	log.Printf("Web API: Adding minion message for path '%s': %s", req.Path, req.Message)
-	if err := s.stateManager.AddMinionMessage(req.Path, req.Message); err != nil {
-		s.writeError(w, fmt.Sprintf("Failed to send message to minion: %v", err), http.StatusInternalServerError)
+	if req.TTLSeconds < 0 {
+		s.writeError(w, "TTL must not be negative", http.StatusBadRequest)
 		return
 	}
-	log.Printf("Web API: Successfully added minion message for path '%s'", req.Path)
 
-	response := map[string]string{
-		"status":  "sent",
-		"path":    req.Path,
-		"message": req.Message,
+	// Minions register under the canonical path of their directory
+	if req.Path != "" {
+		req.Path = fsutil.CanonicalPath(req.Path)
 	}
 
-	json.NewEncoder(w).Encode(response)
+	message := state.MinionMessage{Path: req.Path, Message: req.Message, SendNow: req.SendNow}
+	targets := []string{""}
+	if req.MinionID != "" {
+		minion, err := s.stateManager.GetMinion(req.MinionID)
+		if err != nil {
+			s.writeError(w, "Minion not found", http.StatusNotFound)
+			return
+		}
+		if req.Path != "" && req.Path != fsutil.CanonicalPath(minion.Path) {
+			s.writeError(w, "Minion is not running in this path", http.StatusBadRequest)
+			return
+		}
+		message.Path = minion.Path
+		targets = []string{minion.ID}
+	} else if req.Broadcast {
+		minions, err := s.stateManager.GetMinions(req.Path)
+		if err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to get minions: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// With no minion running yet, the first one to start takes it
+		if len(minions) > 0 {
+			targets = targets[:0]
+			for _, minion := range minions {
+				targets = append(targets, minion.ID)
+			}
+		}
+	}
+
+	// Add message to the minion queue for this path
+	var sent []*state.MinionMessage
+	for _, target := range targets {
+		message.MinionID = target
+		log.Printf("Web API: Adding minion message for path '%s' (minion %q): %s", message.Path, target, req.Message)
+		added, err := s.stateManager.AddMinionMessage(message, time.Duration(req.TTLSeconds)*time.Second)
+		if err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to send message to minion: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Web API: Successfully added minion message %s for path '%s'", added.ID, message.Path)
+		sent = append(sent, added)
+	}
+	s.broadcastMinionMessages()
+
+	if req.Broadcast {
+		json.NewEncoder(w).Encode(sent)
+		return
+	}
+	json.NewEncoder(w).Encode(sent[0])
 }
 
 func (s *Server) handleSystemCommands(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		ok          bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"", false},
+		{"text/plain", false},
+		{"application/x-www-form-urlencoded", false},
+		{"multipart/form-data; boundary=x", false},
+	}
+
+	s := &Server{}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("{}"))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+
+		if got := s.requireJSON(rec, req); got != tt.ok {
+			t.Errorf("requireJSON(%q) = %v, want %v", tt.contentType, got, tt.ok)
+		}
+		if !tt.ok && rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("requireJSON(%q) responded %d, want %d", tt.contentType, rec.Code, http.StatusUnsupportedMediaType)
+		}
+	}
+}
+
+func TestStateChangingPostsRequireJSON(t *testing.T) {
+	s := &Server{}
+	handlers := map[string]http.HandlerFunc{
+		"/api/approvals/approval_1": s.handleApprovalByID,
+		"/api/minion/control":       s.handleMinionControl,
+		"/api/minion/message":       s.handleMinionMessage,
+		"/api/minion/launch":        s.handleMinionLaunch,
+		"/api/schedules":            s.handleSchedules,
+		"/api/schedules/schedule_1": s.handleScheduleByID,
+		"/api/tasks":                s.handleTasks,
+	}
+
+	for path, handler := range handlers {
+		// What a cross-site form can send
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"path":"/repo"}`))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+
+		handler(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("POST %s as text/plain = %d, want %d", path, rec.Code, http.StatusUnsupportedMediaType)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/git"
+	"coding-agent-dashboard/internal/state"
+)
+
+// CreateTaskRequest starts an agent on a new branch of a repository
+type CreateTaskRequest struct {
+	RepositoryID string            `json:"repository_id"`
+	Branch       string            `json:"branch"`
+	Base         string            `json:"base,omitempty"`     // Ref a new branch starts from; HEAD when empty
+	Prompt       string            `json:"prompt"`             // Initial prompt for the agent
+	Command      []string          `json:"command,omitempty"`  // Defaults to claude
+	Model        string            `json:"model,omitempty"`    // Passed as --model
+	Env          map[string]string `json:"env,omitempty"`      // Added to the server's environment
+	Record       bool              `json:"record,omitempty"`   // Record the terminal as an asciicast
+	Approval     bool              `json:"approval,omitempty"` // Install hooks that wait for tool approval
+}
+
+// Task is an agent the dashboard set up: its worktree and the minion running it
+type Task struct {
+	RepositoryID string         `json:"repository_id"`
+	Worktree     state.Worktree `json:"worktree"`
+	Minion       LaunchedMinion `json:"minion"`
+}
+
+// handleTasks creates a worktree for a branch, installs the hooks in it and
+// launches a headless minion running the agent with the prompt, in one request
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.requireJSON(w, r) {
+		return
+	}
+
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.RepositoryID == "" {
+		s.writeError(w, "Repository ID is required", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		s.writeError(w, "Prompt is required", http.StatusBadRequest)
+		return
+	}
+	for key := range req.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			s.writeError(w, fmt.Sprintf("Invalid environment variable name %q", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	repo, err := s.stateManager.GetRepository(req.RepositoryID)
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			s.writeError(w, "Repository not found", http.StatusNotFound)
+		} else {
+			s.writeError(w, fmt.Sprintf("Failed to get repository: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	worktree, err := s.gitManager.CreateWorktree(repo.Path, git.CreateWorktreeOptions{
+		Branch: req.Branch,
+		Base:   req.Base,
+	})
+	if err != nil {
+		s.writeError(w, err.Error(), worktreeErrorStatus(err))
+		return
+	}
+	s.stateManager.AddAction("worktree", fmt.Sprintf("🌳 Created worktree %s on %s", worktree.Path, worktree.Branch))
+
+	// The worktree is left in place if a later step fails, so the task can be
+	// finished by hand or the worktree removed from the dashboard
+	if err := s.installHook(worktree.Path, req.Approval); err != nil {
+		s.writeError(w, fmt.Sprintf("Created worktree %s but failed to install hooks: %v", worktree.Path, err), http.StatusInternalServerError)
+		return
+	}
+
+	minion, err := s.startMinion(LaunchMinionRequest{
+		Path:    worktree.Path,
+		Command: req.Command,
+		Prompt:  req.Prompt,
+		Model:   req.Model,
+		Env:     req.Env,
+		Record:  req.Record,
+	})
+	if err != nil {
+		s.writeError(w, fmt.Sprintf("Created worktree %s but failed to launch minion: %v", worktree.Path, err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Web API: Started task on branch %s of %s in %s (minion %s)", worktree.Branch, repo.Name, worktree.Path, minion.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Task{
+		RepositoryID: repo.ID,
+		Worktree:     *worktree,
+		Minion:       *minion,
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/git"
+	"coding-agent-dashboard/internal/state"
+)
+
+// Tasks launch this binary as a minion, which a test has no use for
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == "--minion" {
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestTaskWorktreeCanBeRemovedWithoutForce(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	repoPath := fsutil.CanonicalPath(filepath.Join(dir, "app"))
+	runGit(t, dir, "init", "-q", "-b", "main", repoPath)
+	if err := os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repoPath, "add", ".gitignore")
+	runGit(t, repoPath, "commit", "-q", "-m", "init")
+
+	stateManager, err := state.NewManager(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to create state manager: %v", err)
+	}
+	defer stateManager.Close()
+	repo, err := stateManager.AddRepository(repoPath, "app")
+	if err != nil {
+		t.Fatalf("failed to add repository: %v", err)
+	}
+
+	s := NewServer(stateManager, git.NewManager())
+
+	body, _ := json.Marshal(CreateTaskRequest{RepositoryID: repo.ID, Branch: "feature/login", Prompt: "hi", Command: []string{"true"}})
+	req := httptest.NewRequest("POST", "/api/tasks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.handleTasks(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/tasks = %d %s", rec.Code, rec.Body)
+	}
+	var task Task
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("failed to decode task: %v", err)
+	}
+	waitForMinionExit(t, s, task.Minion.PID)
+
+	if _, err := os.Stat(filepath.Join(task.Worktree.Path, ".claude", "settings.local.json")); err != nil {
+		t.Errorf("hooks not installed in task worktree: %v", err)
+	}
+	if output := gitOutput(t, task.Worktree.Path, "status", "--porcelain"); output != "" {
+		t.Errorf("task worktree is not clean:\n%s", output)
+	}
+
+	// The main worktree isn't touched either
+	if output := gitOutput(t, repoPath, "status", "--porcelain"); output != "" {
+		t.Errorf("main worktree is not clean:\n%s", output)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/repositories/"+repo.ID+"/worktrees?path="+url.QueryEscape(task.Worktree.Path), nil)
+	rec = httptest.NewRecorder()
+	s.handleWorktrees(rec, req, repo.ID)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE worktree without force = %d %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(task.Worktree.Path); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists", task.Worktree.Path)
+	}
+}
+
+// waitForMinionExit waits until a launched minion has been reaped, so the test
+// doesn't close the store under it
+func waitForMinionExit(t *testing.T, s *Server, pid int) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		s.launchedMutex.Lock()
+		exited := s.launched[pid] != nil && s.launched[pid].ExitedAt != nil
+		s.launchedMutex.Unlock()
+		if exited {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("minion %d did not exit", pid)
+}
+
+// runGit runs a git command for a test, with an identity for commits
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	gitOutput(t, dir, args...)
+}
+
+// gitOutput runs a git command for a test and returns its trimmed output
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/terminal"
+)
+
+// How many output chunks may queue for a browser before it is dropped as too slow
+const terminalViewerBuffer = 256
+
+// terminalReset makes a terminal emulator clear itself before a minion's scrollback is replayed
+const terminalReset = "\x1bc"
+
+// The default origin check stays on: anyone who can open this socket can type into an agent
+var terminalUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// minionTerminal buffers a minion's recent output and fans it out to browser viewers
+type minionTerminal struct {
+	path       string // Working directory of the minion
+	scrollback *terminal.RingBuffer
+	viewers    map[chan []byte]bool
+	updatedAt  time.Time
+}
+
+// terminalClientMessage is sent by the browser over the terminal WebSocket
+type terminalClientMessage struct {
+	Type string `json:"type"` // input or resize
+	Data string `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// handleTerminalOutput records output streamed by a minion and passes it to viewers
+func (s *Server) handleTerminalOutput(conn *ipc.Conn, payload ipc.TerminalPayload) {
+	s.ipc.mutex.Lock()
+	defer s.ipc.mutex.Unlock()
+
+	subscriber := s.subscribedMinionLocked(conn)
+	if subscriber == nil {
+		return
+	}
+
+	term := s.ipc.terminals[subscriber.minion.ID]
+	if term == nil {
+		term = &minionTerminal{
+			path:       subscriber.minion.Path,
+			scrollback: terminal.NewRingBuffer(terminal.DefaultScrollback),
+			viewers:    make(map[chan []byte]bool),
+		}
+		s.ipc.terminals[subscriber.minion.ID] = term
+	}
+	term.updatedAt = time.Now()
+
+	data := payload.Data
+	if payload.Replay {
+		// A reconnecting minion resends its scrollback, so viewers start over from it
+		term.scrollback.Reset(data)
+		data = append([]byte(terminalReset), data...)
+	} else {
+		term.scrollback.Write(data)
+	}
+
+	for viewer := range term.viewers {
+		select {
+		case viewer <- data:
+		default:
+			// Viewer can't keep up, drop it
+			delete(term.viewers, viewer)
+			close(viewer)
+		}
+	}
+}
+
+// closeTerminalLocked forgets a minion's terminal once the minion disconnects, ending
+// the streams of its viewers. A minion that reconnects replays its scrollback.
+// The caller must hold the IPC mutex.
+func (s *Server) closeTerminalLocked(id string) {
+	term := s.ipc.terminals[id]
+	if term == nil {
+		return
+	}
+
+	for viewer := range term.viewers {
+		delete(term.viewers, viewer)
+		close(viewer)
+	}
+	delete(s.ipc.terminals, id)
+}
+
+// subscribedMinionLocked returns the minion a connection subscribed as.
+// The caller must hold the IPC mutex.
+func (s *Server) subscribedMinionLocked(conn *ipc.Conn) *connectedMinion {
+	for _, subscriber := range s.ipc.minions {
+		if subscriber.conn == conn {
+			return subscriber
+		}
+	}
+	return nil
+}
+
+// terminalIDLocked picks the terminal to show: a minion's by ID, or for a path the
+// terminal of the minion that connected last, falling back to the most recently
+// active one. The caller must hold the IPC mutex.
+func (s *Server) terminalIDLocked(path, minionID string) string {
+	if minionID != "" {
+		if _, exists := s.ipc.terminals[minionID]; exists {
+			return minionID
+		}
+		return ""
+	}
+
+	for _, id := range s.minionIDsForPathLocked(path) {
+		if _, exists := s.ipc.terminals[id]; exists {
+			return id
+		}
+	}
+
+	latest := ""
+	for id, term := range s.ipc.terminals {
+		if filepath.Clean(term.path) != filepath.Clean(path) {
+			continue
+		}
+		if latest == "" || term.updatedAt.After(s.ipc.terminals[latest].updatedAt) {
+			latest = id
+		}
+	}
+	return latest
+}
+
+// handleMinionTerminal streams a minion's terminal to the browser over a WebSocket,
+// starting with its recent scrollback, and forwards keystrokes and resizes back to the minion
+func (s *Server) handleMinionTerminal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	minionID := r.URL.Query().Get("minion")
+	if path == "" && minionID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeError(w, "Path or minion is required", http.StatusBadRequest)
+		return
+	}
+
+	s.ipc.mutex.Lock()
+	id := s.terminalIDLocked(path, minionID)
+	s.ipc.mutex.Unlock()
+	if id == "" {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeError(w, "No minion terminal for this path", http.StatusNotFound)
+		return
+	}
+
+	conn, err := terminalUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade terminal connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// Register and snapshot together so no output falls between the replay and the stream
+	viewer := make(chan []byte, terminalViewerBuffer)
+	s.ipc.mutex.Lock()
+	term := s.ipc.terminals[id]
+	path = term.path
+	scrollback := term.scrollback.Bytes()
+	term.viewers[viewer] = true
+	s.ipc.mutex.Unlock()
+
+	defer func() {
+		s.ipc.mutex.Lock()
+		if term.viewers[viewer] {
+			delete(term.viewers, viewer)
+			close(viewer)
+		}
+		s.ipc.mutex.Unlock()
+	}()
+
+	log.Printf("Terminal viewer connected for minion %s in %s", id, path)
+
+	go func() {
+		if err := conn.WriteMessage(websocket.BinaryMessage, scrollback); err != nil {
+			return
+		}
+		for data := range viewer {
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+		// Dropped for falling behind, or the minion went away
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("Terminal viewer for %s disconnected: %v", path, err)
+			return
+		}
+
+		var message terminalClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			log.Printf("Ignoring malformed terminal message: %v", err)
+			continue
+		}
+
+		switch message.Type {
+		case "input":
+			s.sendTerminalMessage(id, ipc.TypeTerminalInput, ipc.TerminalPayload{Data: []byte(message.Data)})
+		case "resize":
+			if message.Cols <= 0 || message.Rows <= 0 {
+				log.Printf("Ignoring invalid terminal size %dx%d", message.Cols, message.Rows)
+				continue
+			}
+			s.sendTerminalMessage(id, ipc.TypeTerminalResize, ipc.TerminalSizePayload{Cols: message.Cols, Rows: message.Rows})
+		default:
+			log.Printf("Ignoring unknown terminal message type %q", message.Type)
+		}
+	}
+}
+
+// sendTerminalMessage passes keystrokes or a resize to a minion
+func (s *Server) sendTerminalMessage(minionID, msgType string, payload interface{}) {
+	conn := s.minionConn("", minionID)
+	if conn == nil {
+		log.Printf("Dropping %s for minion %s: it is not connected", msgType, minionID)
+		return
+	}
+
+	if err := conn.Send(msgType, "", payload); err != nil {
+		log.Printf("Failed to send %s to minion %s: %v", msgType, minionID, err)
+	}
+}
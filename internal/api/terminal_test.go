@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/state"
+)
+
+func TestMinionTerminalIsDroppedOnDisconnect(t *testing.T) {
+	s := NewServer(nil, nil)
+
+	// Only compared by identity
+	conn, other := &ipc.Conn{}, &ipc.Conn{}
+	s.ipc.minions["minion_1"] = &connectedMinion{conn: conn, minion: state.Minion{ID: "minion_1", Path: "/repo"}}
+	s.ipc.minions["minion_2"] = &connectedMinion{conn: other, minion: state.Minion{ID: "minion_2", Path: "/repo"}}
+
+	s.handleTerminalOutput(conn, ipc.TerminalPayload{Data: []byte("hello")})
+	s.handleTerminalOutput(other, ipc.TerminalPayload{Data: []byte("hi")})
+	if len(s.ipc.terminals) != 2 {
+		t.Fatalf("got %d terminals, want 2", len(s.ipc.terminals))
+	}
+
+	viewer := make(chan []byte, terminalViewerBuffer)
+	s.ipc.terminals["minion_1"].viewers[viewer] = true
+
+	s.HandleDisconnect(conn)
+
+	if _, exists := s.ipc.terminals["minion_1"]; exists {
+		t.Error("terminal of a disconnected minion is still kept")
+	}
+	if _, exists := s.ipc.terminals["minion_2"]; !exists {
+		t.Error("terminal of a connected minion was dropped")
+	}
+	if _, open := <-viewer; open {
+		t.Error("viewer of a disconnected minion was not closed")
+	}
+}
+
+func TestMinionTerminalSurvivesReplacedConnection(t *testing.T) {
+	s := NewServer(nil, nil)
+
+	// A restarted minion subscribes again under its ID before the old connection drops
+	old, current := &ipc.Conn{}, &ipc.Conn{}
+	s.ipc.minions["minion_1"] = &connectedMinion{conn: current, minion: state.Minion{ID: "minion_1", Path: "/repo"}}
+	s.handleTerminalOutput(current, ipc.TerminalPayload{Data: []byte("hello"), Replay: true})
+
+	s.HandleDisconnect(old)
+
+	if _, exists := s.ipc.terminals["minion_1"]; !exists {
+		t.Error("terminal was dropped when a replaced connection went away")
+	}
+}
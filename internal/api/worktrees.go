@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"coding-agent-dashboard/internal/git"
+	"coding-agent-dashboard/internal/state"
+)
+
+// CreateWorktreeRequest adds a worktree to a repository
+type CreateWorktreeRequest struct {
+	Branch string `json:"branch"`
+	Base   string `json:"base,omitempty"` // Ref a new branch starts from; HEAD when empty
+	Path   string `json:"path,omitempty"` // Defaults to <repo>-<branch> beside the repository
+}
+
+// handleWorktrees lists, creates and removes the worktrees of a repository at
+// /api/repositories/{id}/worktrees. Removal takes ?path=, and optionally
+// force=true and delete_branch=true.
+func (s *Server) handleWorktrees(w http.ResponseWriter, r *http.Request, id string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	repo, err := s.stateManager.GetRepository(id)
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			s.writeError(w, "Repository not found", http.StatusNotFound)
+		} else {
+			s.writeError(w, fmt.Sprintf("Failed to get repository: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		worktrees, err := s.gitManager.GetWorktrees(repo.Path)
+		if err != nil {
+			s.writeError(w, fmt.Sprintf("Failed to get worktrees: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.addWorktreeStatuses(repo.Path, worktrees)
+		json.NewEncoder(w).Encode(worktrees)
+
+	case "POST":
+		if !s.requireJSON(w, r) {
+			return
+		}
+
+		var req CreateWorktreeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		worktree, err := s.gitManager.CreateWorktree(repo.Path, git.CreateWorktreeOptions{
+			Branch: req.Branch,
+			Base:   req.Base,
+			Path:   req.Path,
+		})
+		if err != nil {
+			s.writeError(w, err.Error(), worktreeErrorStatus(err))
+			return
+		}
+
+		log.Printf("Web API: Created worktree %s on branch %s for %s", worktree.Path, worktree.Branch, repo.Name)
+		s.stateManager.AddAction("worktree", fmt.Sprintf("🌳 Created worktree %s on %s", worktree.Path, worktree.Branch))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(worktree)
+
+	case "DELETE":
+		query := r.URL.Query()
+		path := query.Get("path")
+		if path == "" {
+			s.writeError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+
+		err := s.gitManager.RemoveWorktree(repo.Path, path, git.RemoveWorktreeOptions{
+			Force:        query.Get("force") == "true",
+			DeleteBranch: query.Get("delete_branch") == "true",
+		})
+		if err != nil {
+			s.writeError(w, err.Error(), worktreeErrorStatus(err))
+			return
+		}
+
+		log.Printf("Web API: Removed worktree %s from %s", path, repo.Name)
+		s.stateManager.AddAction("worktree", fmt.Sprintf("🗑️ Removed worktree %s", path))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addWorktreeStatuses fills in the git status of each worktree that still has
+// its directory, comparing branches to the repository's default branch
+func (s *Server) addWorktreeStatuses(repoPath string, worktrees []state.Worktree) {
+	// Without a default branch, worktrees are only compared with their upstreams
+	defaultBranch, _ := s.gitManager.DefaultBranch(repoPath)
+
+	for i := range worktrees {
+		if worktrees[i].Prunable || worktrees[i].Bare {
+			continue
+		}
+		status, err := s.gitManager.GetWorktreeStatus(worktrees[i], defaultBranch)
+		if err != nil {
+			log.Printf("Failed to get git status of %s: %v", worktrees[i].Path, err)
+			continue
+		}
+		worktrees[i].GitStatus = status
+	}
+}
+
+// worktreeErrorStatus picks the HTTP status for a failed worktree change
+func worktreeErrorStatus(err error) int {
+	message := err.Error()
+	switch {
+	case errors.Is(err, git.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, git.ErrExists),
+		strings.Contains(message, "uncommitted changes"),
+		strings.Contains(message, "not merged"),
+		strings.Contains(message, "main worktree"):
+		return http.StatusConflict
+	case strings.Contains(message, "required"),
+		strings.Contains(message, "invalid"),
+		strings.Contains(message, "unknown"),
+		strings.Contains(message, "must be absolute"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,117 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Hook event names sent by Claude Code in the hook_event_name field
+const (
+	HookEventPreToolUse       = "PreToolUse"
+	HookEventPostToolUse      = "PostToolUse"
+	HookEventNotification     = "Notification"
+	HookEventStop             = "Stop"
+	HookEventSubagentStop     = "SubagentStop"
+	HookEventUserPromptSubmit = "UserPromptSubmit"
+	HookEventPreCompact       = "PreCompact"
+	HookEventSessionStart     = "SessionStart"
+	HookEventSessionEnd       = "SessionEnd"
+)
+
+// HookEvents lists every hook event the dashboard registers for
+var HookEvents = []string{
+	HookEventPreToolUse,
+	HookEventPostToolUse,
+	HookEventNotification,
+	HookEventStop,
+	HookEventSubagentStop,
+	HookEventUserPromptSubmit,
+	HookEventPreCompact,
+	HookEventSessionStart,
+	HookEventSessionEnd,
+}
+
+// Permission decisions a PreToolUse hook can return
+const (
+	PermissionAllow = "allow"
+	PermissionDeny  = "deny"
+	PermissionAsk   = "ask"
+)
+
+// HookEvent is the JSON payload Claude Code writes to a hook command's stdin
+type HookEvent struct {
+	HookEventName  string `json:"hook_event_name"`
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	Cwd            string `json:"cwd"`
+
+	// PreToolUse and PostToolUse
+	ToolName     string          `json:"tool_name,omitempty"`
+	ToolInput    json.RawMessage `json:"tool_input,omitempty"`
+	ToolResponse json.RawMessage `json:"tool_response,omitempty"`
+
+	// Notification
+	Message string `json:"message,omitempty"`
+
+	// Stop and SubagentStop
+	StopHookActive bool `json:"stop_hook_active,omitempty"`
+
+	// UserPromptSubmit
+	Prompt string `json:"prompt,omitempty"`
+
+	// PreCompact: trigger is "manual" or "auto"
+	Trigger            string `json:"trigger,omitempty"`
+	CustomInstructions string `json:"custom_instructions,omitempty"`
+
+	// SessionStart: source is "startup", "resume", "clear" or "compact"
+	Source string `json:"source,omitempty"`
+
+	// SessionEnd: reason is "clear", "logout", "prompt_input_exit" or "other"
+	Reason string `json:"reason,omitempty"`
+}
+
+// ParseHookEvent decodes a hook payload and validates that it names its event
+func ParseHookEvent(data []byte) (*HookEvent, error) {
+	var event HookEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode hook event: %w", err)
+	}
+
+	if event.HookEventName == "" {
+		return nil, fmt.Errorf("hook event is missing hook_event_name")
+	}
+
+	return &event, nil
+}
+
+// IsPermissionDecision reports whether decision is one Claude Code understands
+func IsPermissionDecision(decision string) bool {
+	switch decision {
+	case PermissionAllow, PermissionDeny, PermissionAsk:
+		return true
+	}
+	return false
+}
+
+// PreToolUseOutput is the JSON a PreToolUse hook prints to stdout to decide a tool call
+type PreToolUseOutput struct {
+	HookSpecificOutput PreToolUseDecision `json:"hookSpecificOutput"`
+}
+
+// PreToolUseDecision carries the permission decision and the reason shown to the user
+type PreToolUseDecision struct {
+	HookEventName            string `json:"hookEventName"`
+	PermissionDecision       string `json:"permissionDecision"`
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
+}
+
+// NewPreToolUseOutput builds the hook output for a permission decision
+func NewPreToolUseOutput(decision, reason string) PreToolUseOutput {
+	return PreToolUseOutput{
+		HookSpecificOutput: PreToolUseDecision{
+			HookEventName:            HookEventPreToolUse,
+			PermissionDecision:       decision,
+			PermissionDecisionReason: reason,
+		},
+	}
+}
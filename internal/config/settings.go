@@ -0,0 +1,151 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// Storage backends for dashboard state
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+)
+
+// How minions type messages into an agent
+const (
+	InjectAuto  = "auto"  // Paste multi-line messages if the agent has turned on bracketed paste, otherwise type them
+	InjectPaste = "paste" // Always paste multi-line messages, for agents whose output isn't watched
+	InjectType  = "type"  // Always type, sending the newline sequence between lines
+)
+
+// Settings are dashboard-wide options shared by the server, hook and minion processes
+type Settings struct {
+	StorageBackend string `json:"storage_backend"` // json (default) or sqlite
+	// Days of agent status history to keep; 0 keeps history forever
+	StatusHistoryRetentionDays int `json:"status_history_retention_days"`
+	// Message injection by agent type, the base name of the command a minion runs
+	Injection map[string]InjectionSettings `json:"injection,omitempty"`
+}
+
+// InjectionSettings control how a minion types a message into an agent. Empty
+// fields take the defaults.
+type InjectionSettings struct {
+	Mode       string `json:"mode,omitempty"`         // auto (default), paste or type
+	Submit     string `json:"submit,omitempty"`       // Keys sent once at the end to submit the message, default "\r"
+	Newline    string `json:"newline,omitempty"`      // Keys typed for a line break when not pasting, default "\n" (Ctrl-J)
+	KeyDelayMs int    `json:"key_delay_ms,omitempty"` // Pause between typed characters, default 10
+}
+
+// DefaultInjection is used for agent types without their own injection settings
+var DefaultInjection = InjectionSettings{
+	Mode:       InjectAuto,
+	Submit:     "\r",
+	Newline:    "\n",
+	KeyDelayMs: 10,
+}
+
+// DefaultSettings returns the settings used when no settings file exists
+func DefaultSettings() *Settings {
+	return &Settings{
+		StorageBackend:             StorageJSON,
+		StatusHistoryRetentionDays: 30,
+	}
+}
+
+// InjectionFor returns the injection settings for an agent type, such as "claude"
+func (s *Settings) InjectionFor(agentType string) InjectionSettings {
+	injection := DefaultInjection
+	custom, ok := s.Injection[strings.ToLower(agentType)]
+	if !ok {
+		return injection
+	}
+
+	if custom.Mode != "" {
+		injection.Mode = custom.Mode
+	}
+	if custom.Submit != "" {
+		injection.Submit = custom.Submit
+	}
+	if custom.Newline != "" {
+		injection.Newline = custom.Newline
+	}
+	if custom.KeyDelayMs != 0 {
+		injection.KeyDelayMs = custom.KeyDelayMs
+	}
+	return injection
+}
+
+// LoadSettings reads settings.json from the config directory, filling in defaults
+func LoadSettings(configDir string) (*Settings, error) {
+	settings := DefaultSettings()
+
+	data, err := os.ReadFile(settingsFile(configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SaveSettings writes settings.json to the config directory
+func SaveSettings(configDir string, settings *Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(settingsFile(configDir), data, 0644); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks that settings hold supported values
+func (s *Settings) Validate() error {
+	switch s.StorageBackend {
+	case StorageJSON, StorageSQLite:
+	default:
+		return fmt.Errorf("unsupported storage backend %q (use %s or %s)", s.StorageBackend, StorageJSON, StorageSQLite)
+	}
+
+	if s.StatusHistoryRetentionDays < 0 {
+		return fmt.Errorf("status_history_retention_days must not be negative")
+	}
+
+	for agentType, injection := range s.Injection {
+		switch injection.Mode {
+		case "", InjectAuto, InjectPaste, InjectType:
+		default:
+			return fmt.Errorf("unsupported injection mode %q for %s (use %s, %s or %s)", injection.Mode, agentType, InjectAuto, InjectPaste, InjectType)
+		}
+		if injection.KeyDelayMs < 0 {
+			return fmt.Errorf("injection key_delay_ms for %s must not be negative", agentType)
+		}
+	}
+	return nil
+}
+
+func settingsFile(configDir string) string {
+	return filepath.Join(configDir, "settings.json")
+}
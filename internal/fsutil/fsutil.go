@@ -0,0 +1,116 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to a temporary file beside path and renames it into
+// place, so readers see either the old contents or the new ones, never a mix
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file unless it was renamed into place
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set permissions on temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	succeeded = true
+	return nil
+}
+
+// Lock is an exclusive advisory lock guarding a state file
+type Lock struct {
+	file *os.File
+}
+
+// LockFile takes an exclusive lock for path, blocking until it is available. The
+// lock is held on a separate "<path>.lock" file because the state file itself is
+// replaced by WriteFileAtomic.
+func LockFile(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() {
+	unlockFile(l.file)
+	l.file.Close()
+}
+
+// WithLock runs fn while holding the lock for path, typically around a
+// read-modify-write of that file
+func WithLock(path string, fn func() error) error {
+	lock, err := LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return fn()
+}
+
+// CanonicalPath returns an absolute, symlink-free form of path, so the same
+// directory reached through different paths compares equal. The parts of path
+// that don't exist yet are kept as they are.
+func CanonicalPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+
+	parent := filepath.Dir(abs)
+	if parent == abs {
+		return abs
+	}
+	return filepath.Join(CanonicalPath(parent), filepath.Base(abs))
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package fsutil
+
+import "os"
+
+// Windows has no flock; writes are still atomic thanks to WriteFileAtomic, but
+// concurrent read-modify-write cycles are not serialized
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,281 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// What a diff compares
+const (
+	DiffHead      = "head"       // Uncommitted changes: the working tree against HEAD
+	DiffMergeBase = "merge-base" // Everything on the branch: the working tree against its merge-base with the default branch
+	DiffCommit    = "commit"     // A single commit against its first parent
+)
+
+// Patches beyond this size are cut short, so one huge generated file can't
+// swamp the dashboard; the file list still covers every file. Untracked files
+// found after the patch reaches this size aren't diffed at all, so they are
+// listed without line counts.
+const maxDiffPatchSize = 1 << 20
+
+// DiffOptions selects what GetDiff compares
+type DiffOptions struct {
+	Mode   string // DiffHead when empty
+	Base   string // Branch to find the merge-base with; the repository's default branch when empty
+	Commit string // Commit to show, for DiffCommit
+}
+
+// Diff is a unified diff with a summary of each file in it
+type Diff struct {
+	Mode      string     `json:"mode"`
+	Base      string     `json:"base,omitempty"`   // Commit the changes are compared with
+	Commit    string     `json:"commit,omitempty"` // Commit shown, for DiffCommit
+	Files     []DiffFile `json:"files"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Patch     string     `json:"patch"`
+	Truncated bool       `json:"truncated,omitempty"` // Patch was cut at maxDiffPatchSize
+}
+
+// DiffFile summarizes the changes to one file
+type DiffFile struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"` // Where a renamed or copied file came from
+	Status    string `json:"status"`             // added, modified, deleted, renamed, copied, type-changed or untracked
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// GetDiff returns the changes in a worktree: uncommitted ones, everything since
+// its branch left the default branch, or a single commit. Untracked files count as
+// added, except when showing a commit.
+func (g *Manager) GetDiff(worktreePath string, options DiffOptions) (*Diff, error) {
+	if _, err := os.Stat(worktreePath); err != nil {
+		return nil, fmt.Errorf("worktree directory %w: %s", ErrNotFound, worktreePath)
+	}
+
+	diff := &Diff{Mode: options.Mode, Files: []DiffFile{}}
+	if diff.Mode == "" {
+		diff.Mode = DiffHead
+	}
+
+	var args []string
+	switch diff.Mode {
+	case DiffHead:
+		base, err := runGit(worktreePath, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("branch has no commits yet")
+		}
+		diff.Base = base
+		args = []string{"diff", "-M", base}
+
+	case DiffMergeBase:
+		branch := options.Base
+		if branch == "" {
+			defaultBranch, err := g.DefaultBranch(worktreePath)
+			if err != nil {
+				return nil, err
+			}
+			branch = defaultBranch
+		}
+		if strings.HasPrefix(branch, "-") || !g.refExists(worktreePath, branch) {
+			return nil, fmt.Errorf("unknown base branch: %s", branch)
+		}
+		base, err := runGit(worktreePath, "merge-base", "HEAD", branch)
+		if err != nil {
+			return nil, fmt.Errorf("no merge-base between HEAD and %s", branch)
+		}
+		diff.Base = base
+		args = []string{"diff", "-M", base}
+
+	case DiffCommit:
+		if options.Commit == "" {
+			return nil, fmt.Errorf("commit is required")
+		}
+		commit, err := runGit(worktreePath, "rev-parse", "--verify", "--quiet", "--end-of-options", options.Commit+"^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("unknown commit: %s", options.Commit)
+		}
+		diff.Commit = commit
+		if parent, err := runGit(worktreePath, "rev-parse", "--verify", "--quiet", commit+"^1"); err == nil {
+			diff.Base = parent
+		}
+		args = []string{"show", "--format=", "--diff-merges=first-parent", "-M", commit}
+
+	default:
+		return nil, fmt.Errorf("invalid diff mode %q (use %s, %s or %s)", diff.Mode, DiffHead, DiffMergeBase, DiffCommit)
+	}
+
+	numstat, err := runGit(worktreePath, append(args, "--numstat", "-z")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
+	nameStatus, err := runGit(worktreePath, append(args, "--name-status", "-z")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff files: %w", err)
+	}
+	patch, err := gitOutput(worktreePath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", err)
+	}
+	diff.Files = parseDiffFiles(numstat, nameStatus)
+	patches := []string{patch}
+
+	if diff.Mode != DiffCommit {
+		untracked, err := runGit(worktreePath, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list untracked files: %w", err)
+		}
+		patchSize := len(patch)
+		for _, path := range strings.Split(untracked, "\x00") {
+			// A nested repository is listed as its directory; it has nothing to diff here
+			if path == "" || strings.HasSuffix(path, "/") {
+				continue
+			}
+			// The patch would be cut before this file, so don't spend two git runs reading it
+			if patchSize > maxDiffPatchSize {
+				diff.Files = append(diff.Files, DiffFile{Path: path, Status: "untracked"})
+				continue
+			}
+			file, patch, err := untrackedFileDiff(worktreePath, path)
+			if err != nil {
+				return nil, err
+			}
+			diff.Files = append(diff.Files, file)
+			patches = append(patches, patch)
+			patchSize += len(patch)
+		}
+	}
+
+	for _, file := range diff.Files {
+		diff.Additions += file.Additions
+		diff.Deletions += file.Deletions
+	}
+
+	diff.Patch = strings.Join(patches, "")
+	if len(diff.Patch) > maxDiffPatchSize {
+		// Cut at a line end, so the last line shown is whole
+		diff.Patch = diff.Patch[:strings.LastIndex(diff.Patch[:maxDiffPatchSize], "\n")+1]
+		diff.Truncated = true
+	}
+
+	return diff, nil
+}
+
+// parseDiffFiles joins the -z output of --numstat and --name-status for the same diff
+func parseDiffFiles(numstat, nameStatus string) []DiffFile {
+	files := []DiffFile{}
+
+	// --name-status -z: status, then the path, or the old and new paths for renames and copies
+	fields := strings.Split(nameStatus, "\x00")
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "" {
+			continue
+		}
+		code := fields[i][0]
+		file := DiffFile{Status: diffStatusName(code)}
+		if (code == 'R' || code == 'C') && i+2 < len(fields) {
+			file.OldPath, file.Path = fields[i+1], fields[i+2]
+			i += 2
+		} else if i+1 < len(fields) {
+			file.Path = fields[i+1]
+			i++
+		}
+		files = append(files, file)
+	}
+
+	// --numstat -z: "added\tdeleted\tpath", or "added\tdeleted\t" then the old and
+	// new paths for renames and copies; "-" counts mean a binary file
+	fields = strings.Split(numstat, "\x00")
+	for i := 0; i < len(fields); i++ {
+		counts := strings.SplitN(fields[i], "\t", 3)
+		if len(counts) != 3 {
+			continue
+		}
+		path := counts[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+
+		for j := range files {
+			if files[j].Path != path {
+				continue
+			}
+			if counts[0] == "-" {
+				files[j].Binary = true
+			} else {
+				files[j].Additions, _ = strconv.Atoi(counts[0])
+				files[j].Deletions, _ = strconv.Atoi(counts[1])
+			}
+			break
+		}
+	}
+
+	return files
+}
+
+// diffStatusName spells out a --name-status letter
+func diffStatusName(code byte) string {
+	switch code {
+	case 'A':
+		return "added"
+	case 'D':
+		return "deleted"
+	case 'R':
+		return "renamed"
+	case 'C':
+		return "copied"
+	case 'T':
+		return "type-changed"
+	default:
+		return "modified"
+	}
+}
+
+// untrackedFileDiff diffs an untracked file as if it were added
+func untrackedFileDiff(worktreePath, path string) (DiffFile, string, error) {
+	file := DiffFile{Path: path, Status: "untracked"}
+
+	numstat, err := runGitNoIndexDiff(worktreePath, "--numstat", "-z", "--", os.DevNull, path)
+	if err != nil {
+		return file, "", err
+	}
+	counts := strings.SplitN(numstat, "\t", 3)
+	if len(counts) == 3 {
+		if counts[0] == "-" {
+			file.Binary = true
+		} else {
+			file.Additions, _ = strconv.Atoi(counts[0])
+			file.Deletions, _ = strconv.Atoi(counts[1])
+		}
+	}
+
+	patch, err := runGitNoIndexDiff(worktreePath, "--", os.DevNull, path)
+	if err != nil {
+		return file, "", err
+	}
+	return file, patch, nil
+}
+
+// runGitNoIndexDiff runs git diff --no-index, which exits 1 when the files differ
+func runGitNoIndexDiff(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"diff", "--no-index"}, args...)...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", fmt.Errorf("git diff: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return string(output), nil
+}
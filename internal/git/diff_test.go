@@ -0,0 +1,109 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffFiles(t *testing.T) {
+	// Captured from `git diff -M HEAD --numstat -z` and `--name-status -z`
+	numstat := "1\t0\tadded file.txt\x00" +
+		"-\t-\tbin.dat\x00" +
+		"0\t1\tgone.txt\x00" +
+		"1\t0\tmod.txt\x00" +
+		"1\t0\t\x00old name.txt\x00new name.txt\x00"
+	nameStatus := "A\x00added file.txt\x00" +
+		"M\x00bin.dat\x00" +
+		"D\x00gone.txt\x00" +
+		"M\x00mod.txt\x00" +
+		"R083\x00old name.txt\x00new name.txt\x00"
+
+	got := parseDiffFiles(numstat, nameStatus)
+	want := []DiffFile{
+		{Path: "added file.txt", Status: "added", Additions: 1},
+		{Path: "bin.dat", Status: "modified", Binary: true},
+		{Path: "gone.txt", Status: "deleted", Deletions: 1},
+		{Path: "mod.txt", Status: "modified", Additions: 1},
+		{Path: "new name.txt", OldPath: "old name.txt", Status: "renamed", Additions: 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseDiffFiles() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseDiffFilesCopyAndTypeChange(t *testing.T) {
+	numstat := "2\t0\t\x00src/a b.go\x00src/c d.go\x00" +
+		"1\t1\tlink\x00"
+	nameStatus := "C075\x00src/a b.go\x00src/c d.go\x00" +
+		"T\x00link\x00"
+
+	got := parseDiffFiles(numstat, nameStatus)
+	want := []DiffFile{
+		{Path: "src/c d.go", OldPath: "src/a b.go", Status: "copied", Additions: 2},
+		{Path: "link", Status: "type-changed", Additions: 1, Deletions: 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseDiffFiles() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseDiffFilesEmpty(t *testing.T) {
+	if got := parseDiffFiles("", ""); len(got) != 0 {
+		t.Errorf("parseDiffFiles() = %+v, want no files", got)
+	}
+}
+
+func TestGetDiffStopsDiffingUntrackedFilesPastTheCap(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git(t, repo, "init", "-q", "-b", "main")
+	git(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+
+	// Untracked files are diffed in name order, so the large one fills the patch first
+	large := strings.Repeat("line\n", maxDiffPatchSize/5+1)
+	for name, content := range map[string]string{"a-large.txt": large, "b.txt": "b\n", "c.txt": "c\n"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := NewManager().GetDiff(repo, DiffOptions{})
+	if err != nil {
+		t.Fatalf("GetDiff() = %v", err)
+	}
+
+	if !diff.Truncated || len(diff.Patch) > maxDiffPatchSize {
+		t.Errorf("patch of %d bytes, truncated=%v, want it cut at %d", len(diff.Patch), diff.Truncated, maxDiffPatchSize)
+	}
+	want := []DiffFile{
+		{Path: "a-large.txt", Status: "untracked", Additions: maxDiffPatchSize/5 + 1},
+		{Path: "b.txt", Status: "untracked"},
+		{Path: "c.txt", Status: "untracked"},
+	}
+	if len(diff.Files) != len(want) {
+		t.Fatalf("files = %+v, want %+v", diff.Files, want)
+	}
+	for i := range want {
+		if diff.Files[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, diff.Files[i], want[i])
+		}
+	}
+}
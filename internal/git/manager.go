@@ -2,11 +2,10 @@ package git
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	
+	"coding-agent-dashboard/internal/fsutil"
 	"coding-agent-dashboard/internal/state"
 )
 
@@ -16,16 +15,28 @@ func NewManager() *Manager {
 	return &Manager{}
 }
 
+// IsGitRepository reports whether path is the top of a git worktree or a bare
+// repository. It asks git, so linked worktrees and submodules, whose .git is a
+// file, count too.
 func (g *Manager) IsGitRepository(path string) bool {
-	gitDir := filepath.Join(path, ".git")
-	_, err := os.Stat(gitDir)
-	return err == nil
+	info, err := g.Inspect(path)
+	if err != nil {
+		return false
+	}
+
+	path = fsutil.CanonicalPath(path)
+	if info.Bare {
+		return path == info.CommonDir
+	}
+	return path == info.TopLevel
 }
 
 func (g *Manager) GetWorktrees(repoPath string) ([]state.Worktree, error) {
 	if !g.IsGitRepository(repoPath) {
 		return nil, fmt.Errorf("not a git repository: %s", repoPath)
 	}
+	// git lists worktrees by canonical path, whatever path the repository was added by
+	canonicalRepoPath := fsutil.CanonicalPath(repoPath)
 	
 	// Get main repository info
 	mainBranch, err := g.getCurrentBranch(repoPath)
@@ -33,13 +44,12 @@ func (g *Manager) GetWorktrees(repoPath string) ([]state.Worktree, error) {
 		return nil, fmt.Errorf("failed to get main branch: %w", err)
 	}
 	
-	worktrees := []state.Worktree{
-		{
-			Path:   repoPath,
-			Branch: mainBranch,
-			IsMain: true,
-		},
+	mainWorktree := &state.Worktree{
+		Path:   repoPath,
+		Branch: mainBranch,
+		IsMain: true,
 	}
+	worktrees := []state.Worktree{}
 	
 	// Get worktrees using git worktree list
 	cmd := exec.Command("git", "worktree", "list", "--porcelain")
@@ -47,7 +57,7 @@ func (g *Manager) GetWorktrees(repoPath string) ([]state.Worktree, error) {
 	output, err := cmd.Output()
 	if err != nil {
 		// If git worktree list fails, just return the main worktree
-		return worktrees, nil
+		return []state.Worktree{*mainWorktree}, nil
 	}
 	
 	// Parse worktree output
@@ -66,16 +76,17 @@ func (g *Manager) GetWorktrees(repoPath string) ([]state.Worktree, error) {
 		
 		if strings.HasPrefix(line, "worktree ") {
 			path := strings.TrimPrefix(line, "worktree ")
-			if path != repoPath { // Skip main worktree as we already added it
+			if fsutil.CanonicalPath(path) == canonicalRepoPath {
+				// The main worktree is already there; just fill in its details
+				currentWorktree = mainWorktree
+			} else {
 				currentWorktree = &state.Worktree{
 					Path:   path,
 					IsMain: false,
 				}
 			}
-		} else if strings.HasPrefix(line, "branch ") && currentWorktree != nil {
-			branch := strings.TrimPrefix(line, "branch ")
-			branch = strings.TrimPrefix(branch, "refs/heads/")
-			currentWorktree.Branch = branch
+		} else if currentWorktree != nil {
+			parseWorktreeAttribute(currentWorktree, line)
 		}
 	}
 	
@@ -84,7 +95,29 @@ func (g *Manager) GetWorktrees(repoPath string) ([]state.Worktree, error) {
 		worktrees = append(worktrees, *currentWorktree)
 	}
 	
-	return worktrees, nil
+	return append([]state.Worktree{*mainWorktree}, worktrees...), nil
+}
+
+// parseWorktreeAttribute reads one line of a worktree's `git worktree list
+// --porcelain` entry after its path
+func parseWorktreeAttribute(worktree *state.Worktree, line string) {
+	key, value, _ := strings.Cut(line, " ")
+	switch key {
+	case "HEAD":
+		worktree.Head = value
+	case "branch":
+		worktree.Branch = strings.TrimPrefix(value, "refs/heads/")
+	case "bare":
+		worktree.Bare = true
+	case "detached":
+		worktree.Detached = true
+	case "locked":
+		worktree.Locked = true
+		worktree.LockReason = value
+	case "prunable":
+		worktree.Prunable = true
+		worktree.PrunableReason = value
+	}
 }
 
 func (g *Manager) getCurrentBranch(repoPath string) (string, error) {
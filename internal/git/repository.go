@@ -0,0 +1,99 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// RepositoryInfo is what git reports about the repository a path belongs to.
+// Paths are canonical.
+type RepositoryInfo struct {
+	Root           string // Main worktree, or the repository itself if it is bare
+	TopLevel       string // Top of the worktree containing the path; empty in a bare repository
+	GitDir         string // Git directory of that worktree
+	CommonDir      string // Git directory shared by all worktrees of the repository
+	Bare           bool
+	LinkedWorktree bool   // The path is in a worktree added with git worktree add
+	Superproject   string // Worktree of the repository this one is a submodule of, if any
+}
+
+// Inspect asks git which repository a path belongs to, following worktrees,
+// submodules and bare repositories, whose .git is a file or missing altogether
+func (g *Manager) Inspect(path string) (*RepositoryInfo, error) {
+	path = fsutil.CanonicalPath(path)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", path)
+	}
+
+	output, err := runGit(path, "rev-parse", "--git-dir", "--git-common-dir", "--is-bare-repository")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", path)
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("unexpected git rev-parse output in %s: %q", path, output)
+	}
+
+	// Older versions of git print these relative to the directory they ran in
+	absolute := func(dir string) string {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(path, dir)
+		}
+		return fsutil.CanonicalPath(dir)
+	}
+	info := &RepositoryInfo{
+		GitDir:    absolute(lines[0]),
+		CommonDir: absolute(lines[1]),
+		Bare:      lines[2] == "true",
+	}
+
+	if info.Bare {
+		info.Root = info.CommonDir
+		return info, nil
+	}
+
+	// Inside the git directory there is no worktree, so this fails there too
+	topLevel, err := runGit(path, "rev-parse", "--show-toplevel")
+	if err != nil || topLevel == "" {
+		return nil, fmt.Errorf("not inside a git worktree: %s", path)
+	}
+	info.TopLevel = fsutil.CanonicalPath(topLevel)
+	info.LinkedWorktree = info.GitDir != info.CommonDir
+
+	if superproject, err := runGit(path, "rev-parse", "--show-superproject-working-tree"); err == nil && superproject != "" {
+		info.Superproject = fsutil.CanonicalPath(superproject)
+	}
+
+	info.Root = info.TopLevel
+	if info.LinkedWorktree {
+		// git lists the main worktree first, or the repository if it is bare
+		root, err := runGit(path, "worktree", "list", "--porcelain")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list worktrees: %w", err)
+		}
+		first, _, _ := strings.Cut(root, "\n")
+		info.Root = fsutil.CanonicalPath(strings.TrimPrefix(first, "worktree "))
+	}
+
+	return info, nil
+}
+
+// LooksLikeGitRepository is a quick check, without running git, for whether a
+// directory is the top of a repository: it has a .git directory or file, or the
+// layout of a bare repository. Directory suggestions use it, since running git
+// for every directory would be too slow.
+func (g *Manager) LooksLikeGitRepository(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return true
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
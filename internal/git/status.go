@@ -0,0 +1,135 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/state"
+)
+
+// GetWorktreeStatus reports a worktree's changed files, how its branch compares
+// to its upstream and to the repository's default branch, and its last commit.
+// defaultBranch is a full ref as returned by DefaultBranch; it may be empty, or
+// the worktree's own branch, to skip that comparison.
+func (g *Manager) GetWorktreeStatus(worktree state.Worktree, defaultBranch string) (*state.WorktreeStatus, error) {
+	if _, err := os.Stat(worktree.Path); err != nil {
+		return nil, fmt.Errorf("worktree directory %w: %s", ErrNotFound, worktree.Path)
+	}
+
+	output, err := runGit(worktree.Path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	status := parseStatus(output)
+
+	if defaultBranch != "" && defaultBranch != "refs/heads/"+worktree.Branch {
+		counts, err := runGit(worktree.Path, "rev-list", "--left-right", "--count", "HEAD..."+defaultBranch)
+		if err == nil {
+			status.MainBranch = shortRefName(defaultBranch)
+			status.AheadOfMain, status.BehindMain = parseLeftRightCount(counts)
+		}
+	}
+
+	// A branch with no commits yet has no last commit
+	if output, err := runGit(worktree.Path, "log", "-1", "--format=%H%x1f%s%x1f%an%x1f%aI"); err == nil && output != "" {
+		status.LastCommit = parseCommit(output)
+	}
+
+	return status, nil
+}
+
+// DefaultBranch returns the full ref of a repository's default branch: the remote
+// branch origin/HEAD points at, or else the local main or master branch. It is
+// what worktree branches are compared with, whatever the main worktree has
+// checked out.
+func (g *Manager) DefaultBranch(repoPath string) (string, error) {
+	// Set by clone, or by `git remote set-head origin --auto`
+	if ref, err := runGit(repoPath, "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD"); err == nil && g.refExists(repoPath, ref) {
+		return ref, nil
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if ref := "refs/heads/" + branch; g.refExists(repoPath, ref) {
+			return ref, nil
+		}
+	}
+
+	return "", fmt.Errorf("no default branch: origin/HEAD is not set and there is no main or master branch")
+}
+
+// shortRefName turns refs/heads/main into main and refs/remotes/origin/main into origin/main
+func shortRefName(ref string) string {
+	if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return name
+	}
+	return strings.TrimPrefix(ref, "refs/remotes/")
+}
+
+// parseStatus counts the entries of `git status --porcelain=v2 --branch`
+func parseStatus(output string) *state.WorktreeStatus {
+	status := &state.WorktreeStatus{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "#":
+			if len(fields) >= 3 && fields[1] == "branch.upstream" {
+				status.Upstream = fields[2]
+			}
+			if len(fields) >= 4 && fields[1] == "branch.ab" {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+			}
+		case "1", "2":
+			// XY: the index and worktree state, "." when unchanged
+			if len(fields) < 2 || len(fields[1]) != 2 {
+				continue
+			}
+			if fields[1][0] != '.' {
+				status.Staged++
+			}
+			if fields[1][1] != '.' {
+				status.Unstaged++
+			}
+		case "u":
+			status.Conflicted++
+		case "?":
+			status.Untracked++
+		}
+	}
+
+	return status
+}
+
+// parseLeftRightCount reads the "<left>\t<right>" of `git rev-list --left-right --count`
+func parseLeftRightCount(output string) (int, int) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	left, _ := strconv.Atoi(fields[0])
+	right, _ := strconv.Atoi(fields[1])
+	return left, right
+}
+
+// parseCommit reads a commit logged with --format=%H%x1f%s%x1f%an%x1f%aI
+func parseCommit(output string) *state.Commit {
+	fields := strings.SplitN(output, "\x1f", 4)
+	if len(fields) != 4 {
+		return nil
+	}
+
+	commit := &state.Commit{Hash: fields[0], Subject: fields[1], Author: fields[2]}
+	if t, err := time.Parse(time.RFC3339, fields[3]); err == nil {
+		commit.Time = t
+	}
+	return commit
+}
@@ -0,0 +1,150 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/state"
+)
+
+func TestParseStatus(t *testing.T) {
+	output := strings.Join([]string{
+		"# branch.oid 1f0c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c",
+		"# branch.head fix/login",
+		"# branch.upstream origin/fix/login",
+		"# branch.ab +3 -1",
+		"1 M. N... 100644 100644 100644 aaaaaaa bbbbbbb staged.go",
+		"1 .M N... 100644 100644 100644 aaaaaaa aaaaaaa unstaged.go",
+		"1 MM N... 100644 100644 100644 aaaaaaa bbbbbbb both.go",
+		"2 R. N... 100644 100644 100644 aaaaaaa aaaaaaa R100 new name.go\told name.go",
+		"u UU N... 100644 100644 100644 100644 aaaaaaa bbbbbbb ccccccc conflict.go",
+		"? untracked file.txt",
+		"? other.txt",
+		"! ignored.log",
+	}, "\n")
+
+	got := parseStatus(output)
+	want := state.WorktreeStatus{
+		Staged:     3,
+		Unstaged:   2,
+		Untracked:  2,
+		Conflicted: 1,
+		Upstream:   "origin/fix/login",
+		Ahead:      3,
+		Behind:     1,
+	}
+	if *got != want {
+		t.Errorf("parseStatus() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestParseStatusWithoutUpstream(t *testing.T) {
+	got := parseStatus("# branch.oid (initial)\n# branch.head main\n")
+	if *got != (state.WorktreeStatus{}) {
+		t.Errorf("parseStatus() = %+v, want an empty status", *got)
+	}
+}
+
+func TestParseLeftRightCount(t *testing.T) {
+	tests := []struct {
+		output      string
+		left, right int
+	}{
+		{"3\t5", 3, 5},
+		{"0\t0", 0, 0},
+		{"12\t0\n", 12, 0},
+		{"", 0, 0},
+		{"7", 0, 0},
+	}
+
+	for _, tt := range tests {
+		left, right := parseLeftRightCount(tt.output)
+		if left != tt.left || right != tt.right {
+			t.Errorf("parseLeftRightCount(%q) = %d, %d, want %d, %d", tt.output, left, right, tt.left, tt.right)
+		}
+	}
+}
+
+func TestParseCommit(t *testing.T) {
+	got := parseCommit("c7835dd\x1fFix login redirect\x1fAnn Lee\x1f2026-10-16T14:09:11+02:00")
+	if got == nil {
+		t.Fatal("parseCommit() = nil")
+	}
+	want := time.Date(2026, 10, 16, 12, 9, 11, 0, time.UTC)
+	if got.Hash != "c7835dd" || got.Subject != "Fix login redirect" || got.Author != "Ann Lee" || !got.Time.Equal(want) {
+		t.Errorf("parseCommit() = %+v", *got)
+	}
+
+	// A subject may contain anything but the separator
+	if got := parseCommit("c7835dd\x1fMerge: a | b\x1fAnn\x1fnot a time"); got == nil || got.Subject != "Merge: a | b" || !got.Time.IsZero() {
+		t.Errorf("parseCommit() with an odd subject and time = %+v", got)
+	}
+
+	if got := parseCommit("c7835dd\x1fsubject only"); got != nil {
+		t.Errorf("parseCommit() of a short line = %+v, want nil", *got)
+	}
+}
+
+func TestDefaultBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	upstream := filepath.Join(dir, "upstream")
+	git(t, dir, "init", "-q", "-b", "trunk", upstream)
+	git(t, upstream, "commit", "-q", "--allow-empty", "-m", "init")
+
+	g := NewManager()
+
+	// Neither origin/HEAD nor main or master
+	if ref, err := g.DefaultBranch(upstream); err == nil {
+		t.Errorf("DefaultBranch() = %q, want an error", ref)
+	}
+
+	// main or master when there is no origin/HEAD, whatever is checked out
+	git(t, upstream, "branch", "master")
+	git(t, upstream, "checkout", "-q", "-b", "feature")
+	if ref, err := g.DefaultBranch(upstream); err != nil || ref != "refs/heads/master" {
+		t.Errorf("DefaultBranch() = %q, %v, want refs/heads/master", ref, err)
+	}
+	git(t, upstream, "branch", "main")
+	if ref, err := g.DefaultBranch(upstream); err != nil || ref != "refs/heads/main" {
+		t.Errorf("DefaultBranch() = %q, %v, want refs/heads/main", ref, err)
+	}
+
+	// A clone follows origin/HEAD, even with main checked out
+	git(t, upstream, "checkout", "-q", "trunk")
+	clone := filepath.Join(dir, "clone")
+	git(t, dir, "clone", "-q", upstream, clone)
+	git(t, clone, "checkout", "-q", "main")
+	if ref, err := g.DefaultBranch(clone); err != nil || ref != "refs/remotes/origin/trunk" {
+		t.Errorf("DefaultBranch() = %q, %v, want refs/remotes/origin/trunk", ref, err)
+	}
+}
+
+func TestShortRefName(t *testing.T) {
+	for ref, want := range map[string]string{
+		"refs/heads/main":          "main",
+		"refs/heads/feature/login": "feature/login",
+		"refs/remotes/origin/main": "origin/main",
+	} {
+		if got := shortRefName(ref); got != want {
+			t.Errorf("shortRefName(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+// git runs a git command for a test, with an identity for commits
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
@@ -0,0 +1,196 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/state"
+)
+
+// Errors wrapped when a worktree or branch is missing or already there, for callers
+// to tell apart
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// CreateWorktreeOptions describes a worktree to add to a repository
+type CreateWorktreeOptions struct {
+	Branch string // Branch to check out; created if it doesn't exist yet
+	Base   string // Where a new branch starts; HEAD when empty
+	Path   string // Directory for the worktree; WorktreePath when empty
+}
+
+// RemoveWorktreeOptions controls how far RemoveWorktree goes
+type RemoveWorktreeOptions struct {
+	Force        bool // Remove even with uncommitted changes, and delete unmerged branches
+	DeleteBranch bool // Also delete the worktree's branch
+}
+
+// WorktreePath is where a new worktree for a branch goes by default: a sibling of
+// the repository named after both, e.g. ~/src/app-feature-login for branch
+// feature/login of ~/src/app
+func WorktreePath(repoPath, branch string) string {
+	name := strings.NewReplacer("/", "-", "\\", "-", " ", "-").Replace(branch)
+	return filepath.Join(filepath.Dir(repoPath), filepath.Base(repoPath)+"-"+name)
+}
+
+// CreateWorktree adds a worktree to a repository. An existing branch is checked
+// out, or tracked from the one remote that has it; otherwise the branch is
+// created from the base ref.
+func (g *Manager) CreateWorktree(repoPath string, options CreateWorktreeOptions) (*state.Worktree, error) {
+	if !g.IsGitRepository(repoPath) {
+		return nil, fmt.Errorf("not a git repository: %s", repoPath)
+	}
+
+	if options.Branch == "" {
+		return nil, fmt.Errorf("branch is required")
+	}
+	if _, err := runGit(repoPath, "check-ref-format", "--branch", options.Branch); err != nil {
+		return nil, fmt.Errorf("invalid branch name %q", options.Branch)
+	}
+
+	path := options.Path
+	if path == "" {
+		path = WorktreePath(repoPath, options.Branch)
+	} else if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("worktree path must be absolute: %s", path)
+	}
+	path = fsutil.CanonicalPath(path)
+
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("worktree path %w: %s", ErrExists, path)
+	}
+
+	localBranch := g.refExists(repoPath, "refs/heads/"+options.Branch)
+	if localBranch && options.Base != "" {
+		return nil, fmt.Errorf("branch %s %w; leave out the base to check it out", options.Branch, ErrExists)
+	}
+
+	args := []string{"worktree", "add"}
+	if localBranch || (options.Base == "" && g.remoteBranchExists(repoPath, options.Branch)) {
+		// git creates a tracking branch for a branch that only exists on a remote
+		args = append(args, path, options.Branch)
+	} else {
+		base := options.Base
+		if base == "" {
+			base = "HEAD"
+		}
+		if !g.refExists(repoPath, base) {
+			return nil, fmt.Errorf("unknown base ref: %s", base)
+		}
+		args = append(args, "-b", options.Branch, path, base)
+	}
+
+	if _, err := runGit(repoPath, args...); err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	return &state.Worktree{Path: path, Branch: options.Branch}, nil
+}
+
+// RemoveWorktree removes a linked worktree of a repository. It refuses to throw
+// away uncommitted changes or an unmerged branch unless forced.
+func (g *Manager) RemoveWorktree(repoPath, worktreePath string, options RemoveWorktreeOptions) error {
+	worktrees, err := g.GetWorktrees(repoPath)
+	if err != nil {
+		return err
+	}
+
+	var worktree *state.Worktree
+	for i := range worktrees {
+		if fsutil.CanonicalPath(worktrees[i].Path) == fsutil.CanonicalPath(worktreePath) {
+			worktree = &worktrees[i]
+			break
+		}
+	}
+	if worktree == nil {
+		return fmt.Errorf("worktree %w: %s", ErrNotFound, worktreePath)
+	}
+	if worktree.IsMain {
+		return fmt.Errorf("can't remove the main worktree of %s", repoPath)
+	}
+
+	if !options.Force {
+		// A worktree whose directory is already gone has nothing left to lose
+		if _, err := os.Stat(worktree.Path); err == nil {
+			changes, err := runGit(worktree.Path, "status", "--porcelain")
+			if err != nil {
+				return fmt.Errorf("failed to check worktree for changes: %w", err)
+			}
+			if changes != "" {
+				return fmt.Errorf("worktree has uncommitted changes: %s", worktree.Path)
+			}
+		}
+
+		if options.DeleteBranch && worktree.Branch != "" {
+			if _, err := runGit(repoPath, "merge-base", "--is-ancestor", "refs/heads/"+worktree.Branch, "HEAD"); err != nil {
+				return fmt.Errorf("branch %s is not merged into %s", worktree.Branch, repoPath)
+			}
+		}
+	}
+
+	args := []string{"worktree", "remove", worktree.Path}
+	if options.Force {
+		args = []string{"worktree", "remove", "--force", worktree.Path}
+	}
+	if _, err := os.Stat(worktree.Path); os.IsNotExist(err) {
+		args = []string{"worktree", "prune"}
+	}
+	if _, err := runGit(repoPath, args...); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+
+	if options.DeleteBranch && worktree.Branch != "" {
+		// Merged (or forced) above, so -D only skips git checking it again
+		if _, err := runGit(repoPath, "branch", "-D", worktree.Branch); err != nil {
+			return fmt.Errorf("worktree removed but failed to delete branch %s: %w", worktree.Branch, err)
+		}
+	}
+
+	return nil
+}
+
+// refExists reports whether a ref or revision resolves to a commit
+func (g *Manager) refExists(repoPath, ref string) bool {
+	_, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// remoteBranchExists reports whether any remote has a branch of this name
+func (g *Manager) remoteBranchExists(repoPath, branch string) bool {
+	output, err := runGit(repoPath, "for-each-ref", "--format=%(refname)", "refs/remotes/*/"+branch)
+	return err == nil && output != ""
+}
+
+// runGit runs a git command in dir and returns its trimmed output. Errors carry
+// what git printed to stderr.
+func runGit(dir string, args ...string) (string, error) {
+	output, err := gitOutput(dir, args...)
+	return strings.TrimSpace(output), err
+}
+
+// gitOutput runs a git command in dir and returns its output untouched, for
+// output such as patches where whitespace matters
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,38 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktreeErrors(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	git(t, dir, "init", "-q", "-b", "main", repo)
+	git(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+
+	g := NewManager()
+	worktree, err := g.CreateWorktree(repo, CreateWorktreeOptions{Branch: "feature"})
+	if err != nil {
+		t.Fatalf("CreateWorktree() = %v", err)
+	}
+
+	if _, err := g.CreateWorktree(repo, CreateWorktreeOptions{Branch: "other", Path: worktree.Path}); !errors.Is(err, ErrExists) {
+		t.Errorf("CreateWorktree() at an existing path = %v, want ErrExists", err)
+	}
+	if _, err := g.CreateWorktree(repo, CreateWorktreeOptions{Branch: "feature", Base: "main", Path: filepath.Join(dir, "again")}); !errors.Is(err, ErrExists) {
+		t.Errorf("CreateWorktree() of an existing branch from a base = %v, want ErrExists", err)
+	}
+	if err := g.RemoveWorktree(repo, filepath.Join(dir, "missing"), RemoveWorktreeOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveWorktree() of an unknown worktree = %v, want ErrNotFound", err)
+	}
+	if _, err := g.GetDiff(filepath.Join(dir, "missing"), DiffOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDiff() of a missing directory = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,49 @@
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// How long clients wait to connect before falling back to state files
+const dialTimeout = 200 * time.Millisecond
+
+// Conn is one end of a socket connection. Sends are safe for concurrent use;
+// Receive must only be called from one goroutine.
+type Conn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+// Dial connects to the dashboard server's socket
+func Dial(socketPath string) (*Conn, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to dashboard socket: %w", err)
+	}
+	return &Conn{conn: conn}, nil
+}
+
+// Send writes a message with the given type, ID and payload
+func (c *Conn) Send(msgType, id string, payload interface{}) error {
+	msg, err := NewMessage(msgType, id, payload)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return WriteMessage(c.conn, msg)
+}
+
+// Receive blocks until the next message arrives
+func (c *Conn) Receive() (*Message, error) {
+	return ReadMessage(c.conn)
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
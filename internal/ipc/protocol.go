@@ -0,0 +1,165 @@
+package ipc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Message types exchanged between hook/minion processes and the dashboard server
+const (
+	TypeHookEvent       = "hook_event"       // hook -> server: agent status was updated
+	TypeSubscribe       = "subscribe"        // minion -> server: deliver messages for a path
+	TypeMinionMessage   = "minion_message"   // server -> minion: message to inject
+	TypeMessageReceipt  = "message_receipt"  // minion -> server: a message's outcome was recorded
+	TypeWaitApproval    = "wait_approval"    // hook -> server: notify me when an approval is decided
+	TypeApprovalDecided = "approval_decided" // server -> hook: the approval was decided
+	TypeTerminalOutput  = "terminal_output"  // minion -> server: output the agent printed to its PTY
+	TypeTerminalInput   = "terminal_input"   // server -> minion: keystrokes to write to the PTY
+	TypeTerminalResize  = "terminal_resize"  // server -> minion: a browser terminal changed size
+	TypeMinionControl   = "minion_control"   // server -> minion: interrupt, stop or restart the agent
+	TypeControlAck      = "control_ack"      // minion -> server: outcome of a minion_control, with its ID
+)
+
+// Actions a minion_control can ask for
+const (
+	ControlEscape    = "escape"    // Press Escape in the agent's terminal
+	ControlInterrupt = "interrupt" // Press Ctrl-C, or send SIGINT when there is no PTY
+	ControlTerminate = "terminate" // SIGTERM, then SIGKILL if the agent doesn't exit in time
+	ControlKill      = "kill"      // SIGKILL
+	ControlRestart   = "restart"   // Terminate, then start the minion again with the same command line
+)
+
+// Frames larger than this are rejected so a bad peer can't make us allocate unbounded memory
+const maxFrameSize = 4 * 1024 * 1024
+
+// Message is a single frame. On the wire each frame is a 4-byte big-endian
+// length followed by the JSON encoding of the message.
+type Message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// HookEventPayload announces that a hook updated the status for a path
+type HookEventPayload struct {
+	Path  string `json:"path"`
+	Event string `json:"event"`
+}
+
+// SubscribePayload asks the server to push minion messages for a minion
+type SubscribePayload struct {
+	Path     string `json:"path"`
+	MinionID string `json:"minion_id"`
+	PID      int    `json:"pid"`
+}
+
+// MessageReceiptPayload identifies a minion message whose delivery outcome the
+// minion has recorded in the state files
+type MessageReceiptPayload struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// TerminalPayload carries raw terminal bytes. Replay marks output sent on subscribe:
+// the minion's scrollback, which replaces whatever the server had buffered.
+type TerminalPayload struct {
+	Data   []byte `json:"data"`
+	Replay bool   `json:"replay,omitempty"`
+}
+
+// TerminalSizePayload is the size of a terminal in character cells
+type TerminalSizePayload struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+// ControlPayload asks a minion to act on the agent it wraps
+type ControlPayload struct {
+	Action string `json:"action"`
+}
+
+// ControlAckPayload reports whether a minion carried out a control action
+type ControlAckPayload struct {
+	Action string `json:"action"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ApprovalPayload identifies a tool approval
+type ApprovalPayload struct {
+	ID string `json:"id"`
+}
+
+// SocketPath returns the path of the dashboard server's socket
+func SocketPath(configDir string) string {
+	return filepath.Join(configDir, "dashboard.sock")
+}
+
+// NewMessage builds a message with a JSON-encoded payload
+func NewMessage(msgType, id string, payload interface{}) (*Message, error) {
+	msg := &Message{Type: msgType, ID: id}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s payload: %w", msgType, err)
+		}
+		msg.Payload = data
+	}
+	return msg, nil
+}
+
+// Decode unmarshals the message payload into v
+func (m *Message) Decode(v interface{}) error {
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("failed to parse %s payload: %w", m.Type, err)
+	}
+	return nil
+}
+
+// WriteMessage writes a single length-prefixed frame
+func WriteMessage(w io.Writer, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if len(data) > maxFrameSize {
+		return fmt.Errorf("message too large: %d bytes", len(data))
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// ReadMessage reads a single length-prefixed frame
+func ReadMessage(r io.Reader) (*Message, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("message too large: %d bytes", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	return &msg, nil
+}
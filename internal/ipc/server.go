@@ -0,0 +1,113 @@
+package ipc
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+)
+
+// Handler receives messages from connected clients
+type Handler interface {
+	HandleMessage(conn *Conn, msg *Message)
+	HandleDisconnect(conn *Conn)
+}
+
+// Server accepts connections on a Unix domain socket
+type Server struct {
+	socketPath string
+	handler    Handler
+	listener   net.Listener
+	conns      map[*Conn]bool
+	mutex      sync.Mutex
+}
+
+// NewServer creates a server for the given socket path
+func NewServer(socketPath string, handler Handler) *Server {
+	return &Server{
+		socketPath: socketPath,
+		handler:    handler,
+		conns:      make(map[*Conn]bool),
+	}
+}
+
+// Start listens on the socket and accepts connections in the background. A
+// socket left behind by a crashed server is replaced, but a live one is not.
+func (s *Server) Start() error {
+	if conn, err := net.Dial("unix", s.socketPath); err == nil {
+		conn.Close()
+		return fmt.Errorf("another dashboard server is listening on %s", s.socketPath)
+	}
+	os.Remove(s.socketPath)
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	// Only the current user should be able to inject messages into their agents
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	s.listener = listener
+
+	go s.acceptLoop()
+	return nil
+}
+
+// Close stops accepting connections, closes existing ones and removes the socket
+func (s *Server) Close() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	os.Remove(s.socketPath)
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			log.Printf("IPC listener stopped: %v", err)
+			return
+		}
+
+		conn := &Conn{conn: netConn}
+		s.mutex.Lock()
+		s.conns[conn] = true
+		s.mutex.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+// serve reads messages from a client until it disconnects
+func (s *Server) serve(conn *Conn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+
+		conn.Close()
+		s.handler.HandleDisconnect(conn)
+	}()
+
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("IPC connection closed: %v", err)
+			}
+			return
+		}
+		s.handler.HandleMessage(conn, msg)
+	}
+}
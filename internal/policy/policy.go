@@ -0,0 +1,337 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// Rule actions, matching Claude Code's permission decisions
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+	ActionAsk   = "ask"
+)
+
+// Path locations a rule can restrict itself to
+const (
+	LocationInside  = "inside"  // Path is inside the agent's worktree
+	LocationOutside = "outside" // Path is outside the agent's worktree
+)
+
+// Policy scopes, in the order they are layered
+const (
+	ScopeRepository = "repository"
+	ScopeGlobal     = "global"
+)
+
+// Rule matches tool calls and decides them. Every field that is set must match;
+// globs use * for any run of characters (including /) and ? for a single character.
+type Rule struct {
+	Name         string `json:"name"`
+	Action       string `json:"action"`                  // allow, deny or ask
+	Tool         string `json:"tool,omitempty"`          // Tool name glob, e.g. "Bash" or "mcp__*"
+	Command      string `json:"command,omitempty"`       // Bash command glob, e.g. "git push*"
+	CommandRegex string `json:"command_regex,omitempty"` // Bash command regular expression
+	Path         string `json:"path,omitempty"`          // File path glob, matched against absolute paths
+	Location     string `json:"location,omitempty"`      // inside or outside the worktree
+	Reason       string `json:"reason,omitempty"`        // Shown to Claude and the user
+
+	commandRegex *regexp.Regexp
+}
+
+// Policy is an ordered list of rules loaded from one policy file
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Request describes a tool call to evaluate
+type Request struct {
+	ToolName  string
+	Command   string   // Bash command, if any
+	FilePaths []string // Absolute paths the tool touches
+	Worktree  string   // Root of the agent's working tree
+}
+
+// Decision is the outcome of evaluating a request against a rule set
+type Decision struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	Rule   string `json:"rule"`
+	Scope  string `json:"scope"`
+}
+
+// pathFields are the tool input fields that name files or directories
+var pathFields = []string{"file_path", "notebook_path", "path"}
+
+// NewRequest builds a request from a PreToolUse tool name and input, resolving
+// relative paths against the worktree
+func NewRequest(toolName string, toolInput json.RawMessage, worktree string) Request {
+	req := Request{
+		ToolName: toolName,
+		Worktree: worktree,
+	}
+
+	var input map[string]interface{}
+	if len(toolInput) == 0 || json.Unmarshal(toolInput, &input) != nil {
+		return req
+	}
+
+	if command, ok := input["command"].(string); ok {
+		req.Command = command
+	}
+
+	for _, field := range pathFields {
+		path, ok := input[field].(string)
+		if !ok || path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) && worktree != "" {
+			path = filepath.Join(worktree, path)
+		}
+		req.FilePaths = append(req.FilePaths, filepath.Clean(path))
+	}
+
+	return req
+}
+
+// GlobalPath returns the path of the policy applied to every repository
+func GlobalPath(configDir string) string {
+	return filepath.Join(configDir, "policy.json")
+}
+
+// RepositoryPath returns the path of the policy for a single repository
+func RepositoryPath(configDir, repoID string) string {
+	return filepath.Join(configDir, "policies", repoID+".json")
+}
+
+// Load reads a policy file; a missing file is an empty policy
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Policy{Rules: []Rule{}}, nil
+		}
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+
+	return &policy, nil
+}
+
+// Save validates and writes a policy file
+func Save(path string, policy *Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks rule actions and locations and compiles command regexes
+func (p *Policy) Validate() error {
+	if p.Rules == nil {
+		p.Rules = []Rule{}
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+
+		switch rule.Action {
+		case ActionAllow, ActionDeny, ActionAsk:
+		default:
+			return fmt.Errorf("rule %d (%s): action must be allow, deny or ask", i+1, rule.Name)
+		}
+
+		switch rule.Location {
+		case "", LocationInside, LocationOutside:
+		default:
+			return fmt.Errorf("rule %d (%s): location must be inside or outside", i+1, rule.Name)
+		}
+
+		if rule.CommandRegex != "" {
+			re, err := regexp.Compile(rule.CommandRegex)
+			if err != nil {
+				return fmt.Errorf("rule %d (%s): invalid command_regex: %w", i+1, rule.Name, err)
+			}
+			rule.commandRegex = re
+		}
+	}
+
+	return nil
+}
+
+// Evaluate checks a request against layered policies, most specific first. Every
+// matching rule is considered and the most restrictive action wins (deny, then ask,
+// then allow), so a repository policy can never loosen a global deny.
+func Evaluate(req Request, layers map[string]*Policy) *Decision {
+	var best *Decision
+
+	for _, scope := range []string{ScopeRepository, ScopeGlobal} {
+		policy := layers[scope]
+		if policy == nil {
+			continue
+		}
+
+		for _, rule := range policy.Rules {
+			if !rule.Matches(req) {
+				continue
+			}
+
+			if best == nil || severity(rule.Action) > severity(best.Action) {
+				best = &Decision{
+					Action: rule.Action,
+					Reason: rule.describe(),
+					Rule:   rule.Name,
+					Scope:  scope,
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// Matches reports whether every condition set on the rule holds for the request
+func (r *Rule) Matches(req Request) bool {
+	if r.Tool != "" && !globMatch(r.Tool, req.ToolName) {
+		return false
+	}
+
+	if r.Command != "" || r.CommandRegex != "" {
+		if req.Command == "" || !r.matchesCommand(req.Command) {
+			return false
+		}
+	}
+
+	if r.Path != "" || r.Location != "" {
+		if len(req.FilePaths) == 0 {
+			return false
+		}
+
+		matched := false
+		for _, path := range req.FilePaths {
+			if r.matchesPath(path, req.Worktree) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesCommand checks the whole command and each command in a chain or pipeline,
+// so "cd repo && git push" is still caught by a "git push*" rule
+func (r *Rule) matchesCommand(command string) bool {
+	candidates := append([]string{strings.TrimSpace(command)}, splitCommand(command)...)
+
+	for _, candidate := range candidates {
+		if r.Command != "" && globMatch(r.Command, candidate) {
+			return true
+		}
+		if r.commandRegex != nil && r.commandRegex.MatchString(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Rule) matchesPath(path, worktree string) bool {
+	if r.Path != "" && !globMatch(r.Path, path) {
+		return false
+	}
+
+	if r.Location != "" && worktree != "" {
+		inside := path == worktree || strings.HasPrefix(path, strings.TrimSuffix(worktree, "/")+"/")
+		if (r.Location == LocationInside) != inside {
+			return false
+		}
+	}
+
+	return true
+}
+
+// describe returns the reason reported to Claude for a rule
+func (r *Rule) describe() string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	if r.Name != "" {
+		return fmt.Sprintf("Policy rule %q", r.Name)
+	}
+	return "Matched a dashboard policy rule"
+}
+
+// severity orders actions so more restrictive ones win
+func severity(action string) int {
+	switch action {
+	case ActionDeny:
+		return 3
+	case ActionAsk:
+		return 2
+	case ActionAllow:
+		return 1
+	}
+	return 0
+}
+
+var commandSeparator = regexp.MustCompile(`&&|\|\||[;|\n]`)
+
+// splitCommand breaks a shell command line into its individual commands
+func splitCommand(command string) []string {
+	var parts []string
+	for _, part := range commandSeparator.Split(command, -1) {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// globMatch matches s against a glob where * spans any characters and ? matches one
+func globMatch(pattern, s string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, ch := range pattern {
+		switch ch {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	expr.WriteString("$")
+
+	matched, err := regexp.MatchString(expr.String(), s)
+	return err == nil && matched
+}
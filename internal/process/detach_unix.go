@@ -0,0 +1,14 @@
+//go:build !windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Detach starts cmd in a session of its own, so it outlives the process that
+// launched it and isn't hit by signals from that process's terminal
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Windows process creation flags not exported by the syscall package
+const detachedProcess = 0x00000008
+
+// Detach starts cmd without a console and in a process group of its own, so it
+// outlives the process that launched it and doesn't receive its Ctrl-C
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
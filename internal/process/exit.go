@@ -0,0 +1,25 @@
+package process
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// ExitCode returns the exit code behind an exec.Cmd Wait error, following the shell
+// convention of 128 plus the signal number when the process was killed by a signal.
+// It returns -1 if err isn't about how the process exited.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return -1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,56 @@
+package process
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned where processes can't be inspected
+var ErrUnsupported = errors.New("process inspection is not supported on this platform")
+
+// Info describes a running process
+type Info struct {
+	PID       int
+	PPID      int
+	Name      string
+	StartTime uint64 // Clock ticks since boot; distinguishes a reused PID
+	Zombie    bool   // Exited but not yet reaped by its parent
+}
+
+// Shells and wrappers that sit between Claude Code and a hook command
+var launchers = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "fish": true, "ksh": true, "env": true,
+}
+
+// FindAgent returns the process that launched the current hook, skipping over the
+// shells Claude Code runs hook commands through
+func FindAgent() (*Info, error) {
+	pid := os.Getppid()
+	for pid > 1 {
+		info, err := Get(pid)
+		if errors.Is(err, ErrUnsupported) {
+			return &Info{PID: os.Getppid()}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !launchers[info.Name] {
+			return info, nil
+		}
+		pid = info.PPID
+	}
+	return nil, errors.New("no agent process found among hook ancestors")
+}
+
+// Alive reports whether pid is still the process that started at startTime. A zero
+// start time only checks the PID. Processes are assumed alive where they can't be inspected.
+func Alive(pid int, startTime uint64) bool {
+	info, err := Get(pid)
+	if errors.Is(err, ErrUnsupported) {
+		return true
+	}
+	if err != nil || info.Zombie {
+		return false
+	}
+	return startTime == 0 || info.StartTime == startTime
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Get reads a process's details from /proc/<pid>/stat
+func Get(pid int) (*Info, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+
+	// The command name is parenthesized and may itself contain spaces or parentheses
+	stat := string(data)
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("failed to parse stat for process %d", pid)
+	}
+
+	// Fields after the name start at field 3 (state); ppid is 4 and starttime is 22
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("failed to parse stat for process %d", pid)
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse parent of process %d: %w", pid, err)
+	}
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start time of process %d: %w", pid, err)
+	}
+
+	return &Info{
+		PID:       pid,
+		PPID:      ppid,
+		Name:      stat[open+1 : end],
+		StartTime: startTime,
+		Zombie:    fields[0] == "Z",
+	}, nil
+}
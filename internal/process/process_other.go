@@ -0,0 +1,8 @@
+//go:build !linux
+
+package process
+
+// Get is only implemented on Linux, where /proc is available
+func Get(pid int) (*Info, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,293 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// Approval statuses
+const (
+	ApprovalPending   = "pending"
+	ApprovalDecided   = "decided"
+	ApprovalExpired   = "expired"
+	ApprovalAbandoned = "abandoned"
+)
+
+// How long resolved approvals are kept around for the dashboard to show
+const approvalRetention = 1 * time.Hour
+
+// AddToolApproval records a pending approval. Each approval lives in its own file
+// so concurrent hook processes never rewrite each other's requests.
+func (m *Manager) AddToolApproval(path, sessionID, toolName string, toolInput json.RawMessage) (*ToolApproval, error) {
+	approval := ToolApproval{
+		ID:        fmt.Sprintf("approval_%d", time.Now().UnixNano()),
+		Path:      path,
+		SessionID: sessionID,
+		ToolName:  toolName,
+		ToolInput: toolInput,
+		Status:    ApprovalPending,
+		HookPID:   os.Getpid(),
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.saveToolApproval(&approval); err != nil {
+		return nil, err
+	}
+
+	m.notifyStatusChange()
+	return &approval, nil
+}
+
+// GetToolApproval loads a single approval by ID
+func (m *Manager) GetToolApproval(id string) (*ToolApproval, error) {
+	data, err := os.ReadFile(m.getToolApprovalFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("approval %w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read approval file: %w", err)
+	}
+
+	var approval ToolApproval
+	if err := json.Unmarshal(data, &approval); err != nil {
+		return nil, fmt.Errorf("failed to parse approval file: %w", err)
+	}
+
+	return &approval, nil
+}
+
+// GetToolApprovals returns pending and recently resolved approvals, oldest first.
+// It only reads; ReapToolApprovals abandons and deletes approvals.
+func (m *Manager) GetToolApprovals() ([]ToolApproval, error) {
+	ids, err := m.getToolApprovalIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := []ToolApproval{}
+	for _, id := range ids {
+		approval, err := m.GetToolApproval(id)
+		if err != nil {
+			log.Printf("Skipping unreadable approval %s: %v", id, err)
+			continue
+		}
+
+		// Left for the reaper to delete
+		if approvalExpired(*approval) {
+			continue
+		}
+
+		approvals = append(approvals, *approval)
+	}
+
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].CreatedAt.Before(approvals[j].CreatedAt)
+	})
+
+	return approvals, nil
+}
+
+// ReapToolApprovals marks pending approvals whose hook process has gone away as
+// abandoned, and deletes resolved approvals past the retention window. It reports
+// whether any approval the dashboard shows changed.
+func (m *Manager) ReapToolApprovals() (bool, error) {
+	ids, err := m.getToolApprovalIDs()
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for _, id := range ids {
+		abandoned, err := m.reapToolApproval(id)
+		if err != nil {
+			log.Printf("Failed to reap approval %s: %v", id, err)
+			continue
+		}
+		changed = changed || abandoned
+	}
+	return changed, nil
+}
+
+// reapToolApproval abandons or deletes one approval under its lock, since the
+// dashboard and the approval's hook may be finishing it at the same time. It
+// reports whether the approval was abandoned.
+func (m *Manager) reapToolApproval(id string) (bool, error) {
+	approvalFile := m.getToolApprovalFile(id)
+	lock, err := fsutil.LockFile(approvalFile)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock()
+
+	approval, err := m.GetToolApproval(id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if approval.Status == ApprovalPending && !isProcessAlive(approval.HookPID) {
+		now := time.Now()
+		approval.Status = ApprovalAbandoned
+		approval.DecidedAt = &now
+		if err := m.saveToolApproval(approval); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if approvalExpired(*approval) {
+		if err := os.Remove(approvalFile); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to remove approval file: %w", err)
+		}
+		// Anyone still waiting for the lock finds the approval gone once they get it
+		os.Remove(approvalFile + ".lock")
+	}
+	return false, nil
+}
+
+// approvalExpired reports whether a resolved approval is past the retention window
+func approvalExpired(approval ToolApproval) bool {
+	return approval.Status != ApprovalPending && approval.DecidedAt != nil && time.Since(*approval.DecidedAt) > approvalRetention
+}
+
+// getToolApprovalIDs lists the IDs of the approvals on disk
+func (m *Manager) getToolApprovalIDs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.configDir, "approvals"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read approvals directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return ids, nil
+}
+
+// ResolveToolApproval records the decision for a pending approval
+func (m *Manager) ResolveToolApproval(id, decision, reason string) (*ToolApproval, error) {
+	return m.finishToolApproval(id, ApprovalDecided, decision, reason)
+}
+
+// ExpireToolApproval records the decision a hook fell back to after timing out
+func (m *Manager) ExpireToolApproval(id, decision, reason string) (*ToolApproval, error) {
+	return m.finishToolApproval(id, ApprovalExpired, decision, reason)
+}
+
+func (m *Manager) finishToolApproval(id, status, decision, reason string) (*ToolApproval, error) {
+	// Locking would leave a lock file behind for an approval that doesn't exist
+	if _, err := m.GetToolApproval(id); err != nil {
+		return nil, err
+	}
+
+	// The dashboard, a timing-out hook and the reaper can race to finish the same approval
+	lock, err := fsutil.LockFile(m.getToolApprovalFile(id))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	approval, err := m.GetToolApproval(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if approval.Status != ApprovalPending {
+		return nil, fmt.Errorf("approval %s is already %s", id, approval.Status)
+	}
+
+	now := time.Now()
+	approval.Status = status
+	approval.Decision = decision
+	approval.Reason = reason
+	approval.DecidedAt = &now
+
+	if err := m.saveToolApproval(approval); err != nil {
+		return nil, err
+	}
+
+	m.notifyStatusChange()
+	return approval, nil
+}
+
+// saveToolApproval writes an approval to its file
+func (m *Manager) saveToolApproval(approval *ToolApproval) error {
+	approvalFile := m.getToolApprovalFile(approval.ID)
+
+	if err := os.MkdirAll(filepath.Dir(approvalFile), 0755); err != nil {
+		return fmt.Errorf("failed to create approvals directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(approval, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal approval: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(approvalFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write approval file: %w", err)
+	}
+
+	return nil
+}
+
+// getToolApprovalFile returns the path to the file for an approval
+func (m *Manager) getToolApprovalFile(id string) string {
+	return filepath.Join(m.configDir, "approvals", id+".json")
+}
+
+// WriteServerPID records the running dashboard server so hooks know someone can answer approvals
+func (m *Manager) WriteServerPID() error {
+	pidFile := filepath.Join(m.configDir, "server.pid")
+	if err := fsutil.WriteFileAtomic(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed to write server pid file: %w", err)
+	}
+	return nil
+}
+
+// IsServerRunning reports whether a dashboard server process is alive
+func (m *Manager) IsServerRunning() bool {
+	data, err := os.ReadFile(filepath.Join(m.configDir, "server.pid"))
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false
+	}
+
+	return isProcessAlive(pid)
+}
+
+// isProcessAlive checks whether a process exists by sending it signal 0
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+func TestReapToolApprovals(t *testing.T) {
+	m, err := NewManager(newTestConfigDir(t, config.StorageJSON), true)
+	if err != nil {
+		t.Fatalf("failed to create state manager: %v", err)
+	}
+	defer m.Close()
+
+	// A hook process that has already exited
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("can't run a process to stand in for a dead hook: %v", err)
+	}
+	deadPID := cmd.Process.Pid
+
+	longAgo := time.Now().Add(-2 * approvalRetention)
+	approvals := []ToolApproval{
+		{ID: "approval_1", Status: ApprovalPending, HookPID: os.Getpid(), CreatedAt: time.Now()},
+		{ID: "approval_2", Status: ApprovalPending, HookPID: deadPID, CreatedAt: time.Now()},
+		{ID: "approval_3", Status: ApprovalDecided, HookPID: deadPID, CreatedAt: longAgo, DecidedAt: &longAgo},
+	}
+	for i := range approvals {
+		if err := m.saveToolApproval(&approvals[i]); err != nil {
+			t.Fatalf("failed to save approval: %v", err)
+		}
+	}
+
+	// Listing leaves every file as it was, only hiding the expired approval
+	listed, err := m.GetToolApprovals()
+	if err != nil {
+		t.Fatalf("GetToolApprovals() = %v", err)
+	}
+	if len(listed) != 2 || listed[0].Status != ApprovalPending || listed[1].Status != ApprovalPending {
+		t.Errorf("GetToolApprovals() = %+v, want the two pending approvals", listed)
+	}
+	if _, err := os.Stat(m.getToolApprovalFile("approval_3")); err != nil {
+		t.Errorf("GetToolApprovals() removed an approval: %v", err)
+	}
+
+	changed, err := m.ReapToolApprovals()
+	if err != nil || !changed {
+		t.Fatalf("ReapToolApprovals() = %v, %v, want a change", changed, err)
+	}
+
+	if approval, err := m.GetToolApproval("approval_1"); err != nil || approval.Status != ApprovalPending {
+		t.Errorf("approval with a live hook = %+v, %v, want it pending", approval, err)
+	}
+	if approval, err := m.GetToolApproval("approval_2"); err != nil || approval.Status != ApprovalAbandoned || approval.DecidedAt == nil {
+		t.Errorf("approval with a dead hook = %+v, %v, want it abandoned", approval, err)
+	}
+	for _, file := range []string{m.getToolApprovalFile("approval_3"), m.getToolApprovalFile("approval_3") + ".lock"} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("expired approval file %s still exists", file)
+		}
+	}
+
+	// A decision for a deleted approval fails without leaving a lock file behind
+	if _, err := m.ResolveToolApproval("approval_3", "allow", ""); err == nil {
+		t.Error("ResolveToolApproval() of a deleted approval succeeded")
+	}
+	if _, err := os.Stat(m.getToolApprovalFile("approval_3") + ".lock"); !os.IsNotExist(err) {
+		t.Error("ResolveToolApproval() of a deleted approval left a lock file")
+	}
+
+	// Nothing left to do
+	if changed, err := m.ReapToolApprovals(); err != nil || changed {
+		t.Errorf("second ReapToolApprovals() = %v, %v, want no change", changed, err)
+	}
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+// How often the server deletes status history past the retention window
+const historyPruneInterval = 1 * time.Hour
+
+// StatusHistory is an agent's status transitions over a time window
+type StatusHistory struct {
+	Path        string             `json:"path,omitempty"`
+	Since       time.Time          `json:"since"`
+	Until       time.Time          `json:"until"`
+	Transitions []StatusTransition `json:"transitions"`
+	// Seconds spent in each status within the window, summed over the sessions in
+	// the path; only computed for a single path
+	TimeInStatus map[string]float64 `json:"time_in_status,omitempty"`
+	// Seconds spent in each status by each session, keyed by session ID (empty for
+	// entries recorded before sessions were tracked)
+	SessionTimeInStatus map[string]map[string]float64 `json:"session_time_in_status,omitempty"`
+}
+
+// GetStatusHistory returns status transitions since a time, optionally for one path.
+// For a single path it also totals how long each session spent in each status.
+func (m *Manager) GetStatusHistory(path string, since time.Time) (*StatusHistory, error) {
+	transitions, err := m.store.GetStatusTransitions(path, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if transitions == nil {
+		transitions = []StatusTransition{}
+	}
+
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Timestamp.Before(transitions[j].Timestamp)
+	})
+
+	history := &StatusHistory{
+		Path:        path,
+		Since:       since,
+		Until:       time.Now(),
+		Transitions: transitions,
+	}
+
+	if path != "" {
+		// Sessions without transitions in the window spent all of it in their current status
+		current := make(map[string]string)
+		if statuses, err := m.GetAgentStatus(); err == nil {
+			for _, status := range statuses {
+				if status.Path == path {
+					current[status.SessionID] = status.Status
+				}
+			}
+		}
+
+		history.SessionTimeInStatus = timeInStatus(transitions, current, since, history.Until)
+		history.TimeInStatus = make(map[string]float64)
+		for _, totals := range history.SessionTimeInStatus {
+			for status, seconds := range totals {
+				history.TimeInStatus[status] += seconds
+			}
+		}
+	}
+
+	return history, nil
+}
+
+// timeInStatus totals the time each session spent in each status between since
+// and until. Each session's transitions form a timeline of their own, since
+// concurrent sessions in a path change status independently. current holds the
+// status of each session by session ID, for sessions with no transitions.
+func timeInStatus(transitions []StatusTransition, current map[string]string, since, until time.Time) map[string]map[string]float64 {
+	bySession := make(map[string][]StatusTransition)
+	for _, transition := range transitions {
+		bySession[transition.SessionID] = append(bySession[transition.SessionID], transition)
+	}
+
+	totals := make(map[string]map[string]float64)
+	for sessionID, status := range current {
+		if _, ok := bySession[sessionID]; !ok && status != "" {
+			totals[sessionID] = map[string]float64{status: until.Sub(since).Seconds()}
+		}
+	}
+
+	for sessionID, sessionTransitions := range bySession {
+		sessionTotals := make(map[string]float64)
+
+		// The status at the start of the window is the one the first transition left;
+		// a session that started within the window has none
+		status := sessionTransitions[0].OldStatus
+		from := since
+		for _, transition := range sessionTransitions {
+			if status != "" && transition.Timestamp.After(from) {
+				sessionTotals[status] += transition.Timestamp.Sub(from).Seconds()
+			}
+			status = transition.NewStatus
+			from = transition.Timestamp
+		}
+		if status != "" && until.After(from) {
+			sessionTotals[status] += until.Sub(from).Seconds()
+		}
+
+		totals[sessionID] = sessionTotals
+	}
+
+	return totals
+}
+
+// PruneStatusHistory deletes transitions older than the configured retention
+func (m *Manager) PruneStatusHistory() {
+	settings, err := config.LoadSettings(m.configDir)
+	if err != nil {
+		log.Printf("Failed to load settings for history pruning: %v", err)
+		return
+	}
+
+	if settings.StatusHistoryRetentionDays == 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -settings.StatusHistoryRetentionDays)
+	if err := m.store.PruneStatusTransitions(cutoff); err != nil {
+		log.Printf("Failed to prune status history: %v", err)
+	}
+}
+
+// pruneStatusHistoryLoop prunes the history at startup and then periodically
+func (m *Manager) pruneStatusHistoryLoop(stopCh chan bool) {
+	m.PruneStatusHistory()
+
+	ticker := time.NewTicker(historyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			m.PruneStatusHistory()
+		}
+	}
+}
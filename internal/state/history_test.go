@@ -0,0 +1,93 @@
+package state
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimeInStatus(t *testing.T) {
+	since := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time {
+		return since.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	until := at(400)
+
+	tests := []struct {
+		name        string
+		transitions []StatusTransition
+		current     map[string]string
+		want        map[string]map[string]float64
+	}{
+		{
+			name: "concurrent sessions are timed separately",
+			transitions: []StatusTransition{
+				{SessionID: "a", OldStatus: "running", NewStatus: "waiting", Timestamp: at(0)},
+				{SessionID: "b", OldStatus: "idle", NewStatus: "running", Timestamp: at(200)},
+			},
+			want: map[string]map[string]float64{
+				"a": {"waiting": 400},
+				"b": {"idle": 200, "running": 200},
+			},
+		},
+		{
+			name: "status before the first transition counts from the window start",
+			transitions: []StatusTransition{
+				{SessionID: "a", OldStatus: "running", NewStatus: "waiting", Timestamp: at(100)},
+				{SessionID: "a", OldStatus: "waiting", NewStatus: "running", Timestamp: at(250)},
+			},
+			want: map[string]map[string]float64{
+				"a": {"running": 250, "waiting": 150},
+			},
+		},
+		{
+			name: "session started in the window counts from its start",
+			transitions: []StatusTransition{
+				{SessionID: "a", NewStatus: "running", Timestamp: at(300)},
+			},
+			want: map[string]map[string]float64{
+				"a": {"running": 100},
+			},
+		},
+		{
+			name:    "sessions without transitions keep their own current status",
+			current: map[string]string{"a": "waiting", "b": "running"},
+			want: map[string]map[string]float64{
+				"a": {"waiting": 400},
+				"b": {"running": 400},
+			},
+		},
+		{
+			name: "current status is ignored for sessions with transitions",
+			transitions: []StatusTransition{
+				{SessionID: "a", OldStatus: "running", NewStatus: "idle", Timestamp: at(100)},
+			},
+			current: map[string]string{"a": "idle", "b": "waiting"},
+			want: map[string]map[string]float64{
+				"a": {"running": 100, "idle": 300},
+				"b": {"waiting": 400},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := timeInStatus(tt.transitions, tt.current, since, until)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("timeInStatus() = %v, want %v", got, tt.want)
+			}
+			for sessionID, want := range tt.want {
+				if len(got[sessionID]) != len(want) {
+					t.Errorf("session %s: got %v, want %v", sessionID, got[sessionID], want)
+					continue
+				}
+				for status, seconds := range want {
+					if math.Abs(got[sessionID][status]-seconds) > 1e-9 {
+						t.Errorf("session %s %s: got %vs, want %vs", sessionID, status, got[sessionID][status], seconds)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,780 @@
+package state
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+// jsonStore keeps state in JSON files in the config directory. System actions and
+// last messages only live in memory, so they are lost when the server restarts.
+type jsonStore struct {
+	configDir     string
+	systemActions []SystemAction
+	actionsMutex  sync.RWMutex
+}
+
+func newJSONStore(configDir string) (*jsonStore, error) {
+	store := &jsonStore{
+		configDir:     configDir,
+		systemActions: make([]SystemAction, 0),
+	}
+
+	// Create the agent status file if it doesn't exist
+	if err := store.ensureAgentStatusFile(store.getAgentStatusFile()); err != nil {
+		return nil, fmt.Errorf("failed to create agent status file: %w", err)
+	}
+
+	if err := store.migrateLegacyMinionMessages(); err != nil {
+		log.Printf("Failed to migrate minion message files: %v", err)
+	}
+
+	return store, nil
+}
+
+func (s *jsonStore) Close() error {
+	return nil
+}
+
+// UpdateRepositories runs a read-modify-write of the repositories file under its lock
+func (s *jsonStore) UpdateRepositories(update func(repos []Repository) ([]Repository, error)) error {
+	return fsutil.WithLock(s.getRepositoriesFile(), func() error {
+		repos, err := s.GetRepositories()
+		if err != nil {
+			return err
+		}
+
+		repos, err = update(repos)
+		if err != nil {
+			return err
+		}
+
+		return s.SaveRepositories(repos)
+	})
+}
+
+func (s *jsonStore) SaveAgentStatus(statuses []AgentStatus) error {
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent status: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.getAgentStatusFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write agent status file: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAgentStatus holds the status file lock across the read-modify-write, so
+// concurrent hook processes never lose each other's updates
+func (s *jsonStore) UpdateAgentStatus(update func(statuses []AgentStatus) ([]AgentStatus, bool)) error {
+	return fsutil.WithLock(s.getAgentStatusFile(), func() error {
+		statuses, err := s.GetAgentStatus()
+		if err != nil {
+			return err
+		}
+
+		statuses, changed := update(statuses)
+		if !changed {
+			return nil
+		}
+
+		return s.SaveAgentStatus(statuses)
+	})
+}
+
+// StatusVersion is derived from the status file's modification time and size
+func (s *jsonStore) StatusVersion() (string, error) {
+	stat, err := os.Stat(s.getAgentStatusFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat agent status file: %w", err)
+	}
+	return fmt.Sprintf("%d-%d", stat.ModTime().UnixNano(), stat.Size()), nil
+}
+
+// getAgentStatusFile returns the path to the agent status file
+func (s *jsonStore) getAgentStatusFile() string {
+	return filepath.Join(s.configDir, "agent-status.json")
+}
+
+// AddMinionMessage appends a message to the queue file for its path
+func (s *jsonStore) AddMinionMessage(message MinionMessage) error {
+	return fsutil.WithLock(s.getMinionMessageFile(message.Path), func() error {
+		messages, err := s.GetMinionMessages(message.Path)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, message)
+		return s.saveMinionMessages(message.Path, messages)
+	})
+}
+
+// UpdateMinionMessages holds the path's message file lock across the read-modify-write
+func (s *jsonStore) UpdateMinionMessages(path string, update func(messages []MinionMessage) ([]MinionMessage, bool)) error {
+	return fsutil.WithLock(s.getMinionMessageFile(path), func() error {
+		messages, err := s.GetMinionMessages(path)
+		if err != nil {
+			return err
+		}
+
+		messages, changed := update(messages)
+		if !changed {
+			return nil
+		}
+		if len(messages) == 0 {
+			if err := os.Remove(s.getMinionMessageFile(path)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove minion messages file: %w", err)
+			}
+			return nil
+		}
+		return s.saveMinionMessages(path, messages)
+	})
+}
+
+// getAllMinionMessages reads every message across all message files, oldest first
+func (s *jsonStore) getAllMinionMessages() ([]MinionMessage, error) {
+	files, err := filepath.Glob(filepath.Join(s.configDir, "minion-messages", "queue_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var all []MinionMessage
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read minion messages file: %w", err)
+		}
+
+		var messages []MinionMessage
+		if err := json.Unmarshal(data, &messages); err != nil {
+			log.Printf("Skipping unreadable minion messages file %s: %v", file, err)
+			continue
+		}
+		for _, message := range messages {
+			all = append(all, message.withDefaults())
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Timestamp.Before(all[j].Timestamp)
+	})
+	return all, nil
+}
+
+func (s *jsonStore) AddSystemAction(action SystemAction) error {
+	s.actionsMutex.Lock()
+	defer s.actionsMutex.Unlock()
+
+	s.systemActions = append(s.systemActions, action)
+
+	// Keep only the last 50 actions
+	if len(s.systemActions) > maxStoredSystemActions {
+		s.systemActions = s.systemActions[len(s.systemActions)-maxStoredSystemActions:]
+	}
+	return nil
+}
+
+func (s *jsonStore) GetSystemActions(limit int) ([]SystemAction, error) {
+	s.actionsMutex.RLock()
+	defer s.actionsMutex.RUnlock()
+
+	actions := s.systemActions
+	if limit > 0 && len(actions) > limit {
+		actions = actions[len(actions)-limit:]
+	}
+
+	// Return a copy of the slice to prevent race conditions
+	actionsCopy := make([]SystemAction, len(actions))
+	copy(actionsCopy, actions)
+
+	return actionsCopy, nil
+}
+
+// AddStatusTransitions appends transitions to the history log, one JSON object per line
+func (s *jsonStore) AddStatusTransitions(transitions []StatusTransition) error {
+	historyFile := s.getStatusHistoryFile()
+
+	return fsutil.WithLock(historyFile, func() error {
+		f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open status history file: %w", err)
+		}
+		defer f.Close()
+
+		for _, transition := range transitions {
+			data, err := json.Marshal(transition)
+			if err != nil {
+				return fmt.Errorf("failed to marshal status transition: %w", err)
+			}
+			if _, err := f.Write(append(data, '\n')); err != nil {
+				return fmt.Errorf("failed to write status transition: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *jsonStore) GetStatusTransitions(path string, since time.Time) ([]StatusTransition, error) {
+	all, err := s.readStatusHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := []StatusTransition{}
+	for _, transition := range all {
+		if transition.Timestamp.Before(since) || (path != "" && transition.Path != path) {
+			continue
+		}
+		transitions = append(transitions, transition)
+	}
+	return transitions, nil
+}
+
+// PruneStatusTransitions rewrites the history log without transitions older than before
+func (s *jsonStore) PruneStatusTransitions(before time.Time) error {
+	historyFile := s.getStatusHistoryFile()
+
+	return fsutil.WithLock(historyFile, func() error {
+		all, err := s.readStatusHistory()
+		if err != nil {
+			return err
+		}
+
+		var kept []byte
+		pruned := 0
+		for _, transition := range all {
+			if transition.Timestamp.Before(before) {
+				pruned++
+				continue
+			}
+			data, err := json.Marshal(transition)
+			if err != nil {
+				return fmt.Errorf("failed to marshal status transition: %w", err)
+			}
+			kept = append(append(kept, data...), '\n')
+		}
+
+		if pruned == 0 {
+			return nil
+		}
+		return fsutil.WriteFileAtomic(historyFile, kept, 0644)
+	})
+}
+
+// readStatusHistory reads every transition in the history log, skipping malformed lines
+func (s *jsonStore) readStatusHistory() ([]StatusTransition, error) {
+	f, err := os.Open(s.getStatusHistoryFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []StatusTransition{}, nil
+		}
+		return nil, fmt.Errorf("failed to open status history file: %w", err)
+	}
+	defer f.Close()
+
+	var transitions []StatusTransition
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var transition StatusTransition
+		if err := json.Unmarshal(scanner.Bytes(), &transition); err != nil {
+			continue
+		}
+		transitions = append(transitions, transition)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read status history file: %w", err)
+	}
+	return transitions, nil
+}
+
+// getStatusHistoryFile returns the path to the status transition log
+func (s *jsonStore) getStatusHistoryFile() string {
+	return filepath.Join(s.configDir, "status-history.jsonl")
+}
+
+// SaveLastMessage is a no-op: the JSON store keeps last messages only in the
+// Manager's in-memory cache
+func (s *jsonStore) SaveLastMessage(key, message, fullMessage string) error {
+	return nil
+}
+
+func (s *jsonStore) GetLastMessages() (map[string]LastMessage, error) {
+	return map[string]LastMessage{}, nil
+}
+
+func (s *jsonStore) GetRepositories() ([]Repository, error) {
+	repoFile := s.getRepositoriesFile()
+
+	if _, err := os.Stat(repoFile); os.IsNotExist(err) {
+		log.Printf("Repository file does not exist: %s", repoFile)
+		return []Repository{}, nil
+	}
+
+	data, err := os.ReadFile(repoFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repositories file: %w", err)
+	}
+
+	var repos []Repository
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return nil, fmt.Errorf("failed to parse repositories file: %w", err)
+	}
+
+	return repos, nil
+}
+
+func (s *jsonStore) SaveRepositories(repos []Repository) error {
+	repoFile := s.getRepositoriesFile()
+
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal repositories: %w", err)
+	}
+
+	log.Printf("Saving repositories to: %s", repoFile)
+	if err := fsutil.WriteFileAtomic(repoFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write repositories file: %w", err)
+	}
+
+	log.Printf("Successfully saved %d repositories", len(repos))
+	return nil
+}
+
+// getRepositoriesFile returns the path to the repositories file
+func (s *jsonStore) getRepositoriesFile() string {
+	return filepath.Join(s.configDir, "repositories.json")
+}
+
+func (s *jsonStore) GetAgentStatus() ([]AgentStatus, error) {
+	statusFile := s.getAgentStatusFile()
+
+	if _, err := os.Stat(statusFile); os.IsNotExist(err) {
+		return []AgentStatus{}, nil
+	}
+
+	data, err := os.ReadFile(statusFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent status file: %w", err)
+	}
+
+	var statuses []AgentStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		log.Printf("Agent status JSON is corrupted, attempting recovery: %v", err)
+
+		// Try to recover from common corruption patterns
+		recoveredStatuses, recoveryErr := s.recoverCorruptedAgentStatus(data)
+		if recoveryErr != nil {
+			// If recovery fails, start fresh
+			log.Printf("Recovery failed, starting with empty status: %v", recoveryErr)
+			return []AgentStatus{}, nil
+		}
+
+		log.Printf("Successfully recovered %d agent statuses", len(recoveredStatuses))
+
+		// Save the recovered data
+		if saveErr := s.SaveAgentStatus(recoveredStatuses); saveErr != nil {
+			log.Printf("Failed to save recovered agent status: %v", saveErr)
+		}
+
+		return recoveredStatuses, nil
+	}
+
+	return statuses, nil
+}
+
+// GetMinionMessages gets all messages for a minion in a specific directory
+func (s *jsonStore) GetMinionMessages(path string) ([]MinionMessage, error) {
+	if path == "" {
+		return s.getAllMinionMessages()
+	}
+
+	messageFile := s.getMinionMessageFile(path)
+
+	if _, err := os.Stat(messageFile); os.IsNotExist(err) {
+		return []MinionMessage{}, nil
+	}
+
+	data, err := os.ReadFile(messageFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read minion messages file: %w", err)
+	}
+
+	var messages []MinionMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse minion messages file: %w", err)
+	}
+
+	for i := range messages {
+		messages[i] = messages[i].withDefaults()
+	}
+	return messages, nil
+}
+
+// saveMinionMessages saves messages to the file for a specific directory
+func (s *jsonStore) saveMinionMessages(path string, messages []MinionMessage) error {
+	messageFile := s.getMinionMessageFile(path)
+	log.Printf("Saving minion messages to file: %s (for path: %s)", messageFile, path)
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(messageFile), 0755); err != nil {
+		return fmt.Errorf("failed to create minion messages directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal minion messages: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(messageFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write minion messages file: %w", err)
+	}
+
+	return nil
+}
+
+// getMinionMessageFile returns the path to the message file for a specific directory.
+// The name is a hash of the path, so paths like /a_b and /a/b get separate files.
+func (s *jsonStore) getMinionMessageFile(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(s.configDir, "minion-messages", fmt.Sprintf("queue_%x.json", sum[:16]))
+}
+
+// migrateLegacyMinionMessages moves messages out of the files named after their
+// path with separators replaced, where two paths could share a file, into the
+// file for each message's own path
+func (s *jsonStore) migrateLegacyMinionMessages() error {
+	files, err := filepath.Glob(filepath.Join(s.configDir, "minion-messages", "messages_*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		err := fsutil.WithLock(file, func() error {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil // Another process migrated it first
+				}
+				return fmt.Errorf("failed to read minion messages file: %w", err)
+			}
+
+			var messages []MinionMessage
+			if err := json.Unmarshal(data, &messages); err != nil {
+				return fmt.Errorf("failed to parse minion messages file %s: %w", file, err)
+			}
+
+			byPath := make(map[string][]MinionMessage)
+			for _, message := range messages {
+				byPath[message.Path] = append(byPath[message.Path], message)
+			}
+			for path, legacy := range byPath {
+				err := s.UpdateMinionMessages(path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+					seen := make(map[string]bool)
+					for _, message := range messages {
+						seen[message.ID] = true
+					}
+					for _, message := range legacy {
+						if !seen[message.ID] {
+							messages = append(messages, message.withDefaults())
+						}
+					}
+					sort.SliceStable(messages, func(i, j int) bool {
+						return messages[i].Timestamp.Before(messages[j].Timestamp)
+					})
+					return messages, true
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			log.Printf("Migrated %d minion messages from %s", len(messages), file)
+			return os.Remove(file)
+		})
+		if err != nil {
+			return err
+		}
+		os.Remove(file + ".lock")
+	}
+	return nil
+}
+
+// recoverCorruptedAgentStatus attempts to recover from common JSON corruption patterns
+func (s *jsonStore) recoverCorruptedAgentStatus(data []byte) ([]AgentStatus, error) {
+	dataStr := string(data)
+
+	// Common corruption: extra ']' at the end
+	if strings.HasSuffix(dataStr, "]]") {
+		log.Printf("Detected double-bracket corruption, attempting fix")
+		fixedData := []byte(strings.TrimSuffix(dataStr, "]"))
+
+		var statuses []AgentStatus
+		if err := json.Unmarshal(fixedData, &statuses); err == nil {
+			// Successfully fixed, but remove old fields if they exist
+			return s.cleanOldFields(statuses), nil
+		}
+	}
+
+	// Try to parse as old format and convert
+	type OldAgentStatus struct {
+		Path            string    `json:"path"`
+		Status          string    `json:"status"`
+		LastActivity    time.Time `json:"last_activity"`
+		PID             int       `json:"pid,omitempty"`
+		SessionID       string    `json:"session_id,omitempty"`
+		TranscriptPath  string    `json:"transcript_path,omitempty"`
+		LastMessage     string    `json:"last_message,omitempty"`
+		FullLastMessage string    `json:"full_last_message,omitempty"`
+	}
+
+	var oldStatuses []OldAgentStatus
+	if err := json.Unmarshal(data, &oldStatuses); err == nil {
+		log.Printf("Successfully parsed as old format, converting to new format")
+		var newStatuses []AgentStatus
+		for _, old := range oldStatuses {
+			newStatus := AgentStatus{
+				Path:           old.Path,
+				Status:         old.Status,
+				LastActivity:   old.LastActivity,
+				PID:            old.PID,
+				SessionID:      old.SessionID,
+				TranscriptPath: old.TranscriptPath,
+			}
+			newStatuses = append(newStatuses, newStatus)
+		}
+		return newStatuses, nil
+	}
+
+	return nil, fmt.Errorf("unable to recover corrupted JSON")
+}
+
+// cleanOldFields removes old message fields if they exist
+func (s *jsonStore) cleanOldFields(statuses []AgentStatus) []AgentStatus {
+	// The statuses are already in the correct format, just return them
+	return statuses
+}
+
+// ensureAgentStatusFile creates the agent status file if it doesn't exist
+func (s *jsonStore) ensureAgentStatusFile(statusFile string) error {
+	// Check if file already exists
+	if _, err := os.Stat(statusFile); err == nil {
+		return nil // File already exists
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check status file: %w", err)
+	}
+
+	// Create the config directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(statusFile), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Check again under the lock, so a hook that started at the same time and has
+	// already saved its status isn't overwritten with an empty file
+	return fsutil.WithLock(statusFile, func() error {
+		if _, err := os.Stat(statusFile); err == nil {
+			return nil
+		}
+		return s.createAgentStatusFile(statusFile)
+	})
+}
+
+// createAgentStatusFile writes an empty agent status file
+func (s *jsonStore) createAgentStatusFile(statusFile string) error {
+	// Create empty agent status file
+	emptyStatuses := []AgentStatus{}
+	data, err := json.MarshalIndent(emptyStatuses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal empty agent status: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(statusFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to create agent status file: %w", err)
+	}
+
+	log.Printf("Created agent status file: %s", statusFile)
+	return nil
+}
+
+// SaveMinion writes a minion to its own file, so minions starting and stopping
+// never rewrite each other's entries
+func (s *jsonStore) SaveMinion(minion Minion) error {
+	data, err := json.MarshalIndent(minion, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal minion: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.getMinionFile(minion.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write minion file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *jsonStore) DeleteMinion(id string) error {
+	if err := os.Remove(s.getMinionFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove minion file: %w", err)
+	}
+	return nil
+}
+
+func (s *jsonStore) GetMinion(id string) (*Minion, error) {
+	data, err := os.ReadFile(s.getMinionFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("minion %w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read minion file: %w", err)
+	}
+
+	var minion Minion
+	if err := json.Unmarshal(data, &minion); err != nil {
+		return nil, fmt.Errorf("failed to parse minion file: %w", err)
+	}
+
+	return &minion, nil
+}
+
+// GetMinions reads every minion file, skipping unreadable ones
+func (s *jsonStore) GetMinions() ([]Minion, error) {
+	entries, err := os.ReadDir(filepath.Join(s.configDir, "minions"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Minion{}, nil
+		}
+		return nil, fmt.Errorf("failed to read minions directory: %w", err)
+	}
+
+	minions := []Minion{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		minion, err := s.GetMinion(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			// Removed since the directory was read
+			if !errors.Is(err, ErrNotFound) {
+				log.Printf("Skipping unreadable minion %s: %v", entry.Name(), err)
+			}
+			continue
+		}
+		minions = append(minions, *minion)
+	}
+
+	return minions, nil
+}
+
+// getMinionFile returns the path to the file for a minion
+func (s *jsonStore) getMinionFile(id string) string {
+	return filepath.Join(s.configDir, "minions", id+".json")
+}
+
+// SaveSchedule writes a schedule to its own file
+func (s *jsonStore) SaveSchedule(schedule MessageSchedule) error {
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.getScheduleFile(schedule.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule file: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSchedule holds the schedule file's lock across the read-modify-write
+func (s *jsonStore) UpdateSchedule(id string, update func(schedule *MessageSchedule) bool) error {
+	if _, err := s.GetSchedule(id); err != nil {
+		return err
+	}
+
+	return fsutil.WithLock(s.getScheduleFile(id), func() error {
+		// Deleted while we waited for the lock
+		schedule, err := s.GetSchedule(id)
+		if err != nil {
+			return err
+		}
+		if !update(schedule) {
+			return nil
+		}
+		return s.SaveSchedule(*schedule)
+	})
+}
+
+func (s *jsonStore) DeleteSchedule(id string) error {
+	scheduleFile := s.getScheduleFile(id)
+	err := fsutil.WithLock(scheduleFile, func() error {
+		if err := os.Remove(scheduleFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove schedule file: %w", err)
+		}
+		return nil
+	})
+	os.Remove(scheduleFile + ".lock")
+	return err
+}
+
+func (s *jsonStore) GetSchedule(id string) (*MessageSchedule, error) {
+	data, err := os.ReadFile(s.getScheduleFile(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("schedule %w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read schedule file: %w", err)
+	}
+
+	var schedule MessageSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule file: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// GetSchedules reads every schedule file, skipping unreadable ones
+func (s *jsonStore) GetSchedules() ([]MessageSchedule, error) {
+	entries, err := os.ReadDir(filepath.Join(s.configDir, "schedules"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []MessageSchedule{}, nil
+		}
+		return nil, fmt.Errorf("failed to read schedules directory: %w", err)
+	}
+
+	schedules := []MessageSchedule{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		schedule, err := s.GetSchedule(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			log.Printf("Skipping unreadable schedule %s: %v", entry.Name(), err)
+			continue
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	return schedules, nil
+}
+
+// getScheduleFile returns the path to the file for a schedule
+func (s *jsonStore) getScheduleFile(id string) string {
+	return filepath.Join(s.configDir, "schedules", id+".json")
+}
@@ -0,0 +1,169 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+// Number of simultaneous hook writers in the concurrency tests
+const concurrentWriters = 48
+
+// Environment of a hook writer subprocess started by TestConcurrentHookProcesses
+const (
+	writerDirEnv = "STATE_TEST_WRITER_DIR"
+	writerIDEnv  = "STATE_TEST_WRITER_ID"
+)
+
+// writeHookStatus adds one session's status the way a hook process does: with a
+// manager of its own, opened and closed around a single update
+func writeHookStatus(configDir string, writer int) error {
+	manager, err := NewManager(configDir, true)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	sessionID := fmt.Sprintf("session-%d", writer)
+	cause := TransitionCause{Source: "hook", Event: "UserPromptSubmit", SessionID: sessionID}
+	return manager.UpdateAgentStatus(cause, func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		return append(statuses, AgentStatus{
+			Path:         "/repo",
+			Status:       "running",
+			SessionID:    sessionID,
+			LastActivity: time.Now(),
+		}), true
+	})
+}
+
+// TestHookWriterProcess is the body of a hook writer subprocess; it is skipped
+// when run directly
+func TestHookWriterProcess(t *testing.T) {
+	configDir := os.Getenv(writerDirEnv)
+	if configDir == "" {
+		t.Skip("only runs as a subprocess of TestConcurrentHookProcesses")
+	}
+
+	writer, err := strconv.Atoi(os.Getenv(writerIDEnv))
+	if err != nil {
+		t.Fatalf("invalid writer ID: %v", err)
+	}
+	if err := writeHookStatus(configDir, writer); err != nil {
+		t.Fatalf("writer %d failed: %v", writer, err)
+	}
+}
+
+func TestConcurrentHookProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts dozens of processes")
+	}
+
+	for _, backend := range []string{config.StorageJSON, config.StorageSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			configDir := newTestConfigDir(t, backend)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, concurrentWriters)
+			for i := 0; i < concurrentWriters; i++ {
+				wg.Add(1)
+				go func(writer int) {
+					defer wg.Done()
+					cmd := exec.Command(os.Args[0], "-test.run=^TestHookWriterProcess$")
+					cmd.Env = append(os.Environ(), writerDirEnv+"="+configDir, writerIDEnv+"="+strconv.Itoa(writer))
+					if output, err := cmd.CombinedOutput(); err != nil {
+						errs <- fmt.Errorf("writer %d: %v\n%s", writer, err, output)
+					}
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			assertNoLostUpdates(t, configDir)
+		})
+	}
+}
+
+func TestConcurrentHookManagers(t *testing.T) {
+	for _, backend := range []string{config.StorageJSON, config.StorageSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			configDir := newTestConfigDir(t, backend)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, concurrentWriters)
+			for i := 0; i < concurrentWriters; i++ {
+				wg.Add(1)
+				go func(writer int) {
+					defer wg.Done()
+					if err := writeHookStatus(configDir, writer); err != nil {
+						errs <- fmt.Errorf("writer %d: %w", writer, err)
+					}
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			assertNoLostUpdates(t, configDir)
+		})
+	}
+}
+
+// newTestConfigDir returns an empty config directory using the given backend
+func newTestConfigDir(t *testing.T, backend string) string {
+	t.Helper()
+
+	configDir := t.TempDir()
+	settings := config.DefaultSettings()
+	settings.StorageBackend = backend
+	if err := config.SaveSettings(configDir, settings); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+	return configDir
+}
+
+// assertNoLostUpdates checks that every writer's status and transition was kept
+func assertNoLostUpdates(t *testing.T, configDir string) {
+	t.Helper()
+
+	store, err := OpenStore(configDir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	statuses, err := store.GetAgentStatus()
+	if err != nil {
+		t.Fatalf("failed to read agent status: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, status := range statuses {
+		seen[status.SessionID] = true
+	}
+	for i := 0; i < concurrentWriters; i++ {
+		if sessionID := fmt.Sprintf("session-%d", i); !seen[sessionID] {
+			t.Errorf("status for %s was lost", sessionID)
+		}
+	}
+	if len(statuses) != concurrentWriters {
+		t.Errorf("got %d statuses, want %d", len(statuses), concurrentWriters)
+	}
+
+	transitions, err := store.GetStatusTransitions("/repo", time.Time{})
+	if err != nil {
+		t.Fatalf("failed to read status history: %v", err)
+	}
+	if len(transitions) != concurrentWriters {
+		t.Errorf("got %d transitions, want %d", len(transitions), concurrentWriters)
+	}
+}
@@ -1,7 +1,6 @@
 package state
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -13,123 +12,131 @@ import (
 	"github.com/fsnotify/fsnotify"
 
 	"coding-agent-dashboard/internal/claude"
+	"coding-agent-dashboard/internal/fsutil"
 )
 
 type StatusChangeCallback func()
 
+// MinionMessageDeliverer is told when a message is queued for a path, so it can
+// hand it straight to a connected minion instead of waiting for the minion to poll
+type MinionMessageDeliverer func(path string)
+
+// FileWatcher polls the store for agent status changes made by other processes
 type FileWatcher struct {
-	statusFile string
-	manager    *Manager
-	stopCh     chan bool
+	manager *Manager
+	stopCh  chan bool
 }
 
 type TranscriptWatcher struct {
 	manager            *Manager
-	agentStatusPath    string
-	agentWatcher       *fsnotify.Watcher
 	transcriptWatchers map[string]*fsnotify.Watcher // sessionID -> watcher
-	projectToSession   map[string]string            // project path -> current sessionID
 	knownRepos         map[string]bool              // path -> true (for filtering)
 	mutex              sync.RWMutex
 	stopCh             chan bool
 }
 
 type Manager struct {
-	configDir         string
-	changeCallbacks   []StatusChangeCallback
-	fileWatcher       *FileWatcher
-	systemActions     []SystemAction // In-memory storage for system actions
-	actionsMutex      sync.RWMutex   // Mutex for thread-safe access to actions
-	transcriptWatcher *TranscriptWatcher
-	transcriptParser  *claude.TranscriptParser
-	lastMessages      map[string]string // In-memory storage for last messages (path -> message)
-	fullLastMessages  map[string]string // In-memory storage for full last messages (path -> message)
-	messagesMutex     sync.RWMutex     // Mutex for thread-safe access to messages
+	configDir           string
+	store               Store
+	changeCallbacks     []StatusChangeCallback
+	fileWatcher         *FileWatcher
+	transcriptWatcher   *TranscriptWatcher
+	transcriptParser    *claude.TranscriptParser
+	lastMessages        map[string]string // In-memory cache of last messages (status key -> message)
+	fullLastMessages    map[string]string // In-memory cache of full last messages (status key -> message)
+	unsavedLastMessages map[string]bool   // Status keys whose cached messages haven't been persisted yet
+	messagesMutex       sync.RWMutex      // Mutex for thread-safe access to messages
+	deliverMessage      MinionMessageDeliverer
 }
 
-
 func NewManager(configDir string, hookMode bool) (*Manager, error) {
-	manager := &Manager{
-		configDir:        configDir,
-		changeCallbacks:  make([]StatusChangeCallback, 0),
-		systemActions:    make([]SystemAction, 0),
-		transcriptParser: claude.NewTranscriptParser(),
-		lastMessages:     make(map[string]string),
-		fullLastMessages: make(map[string]string),
+	store, err := OpenStore(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
 	}
-	
-	// Set up file watcher for agent status file
-	statusFile := filepath.Join(configDir, "agent-status.json")
-	
-	// Create the agent status file if it doesn't exist
-	if err := manager.ensureAgentStatusFile(statusFile); err != nil {
-		return nil, fmt.Errorf("failed to create agent status file: %w", err)
+
+	manager := &Manager{
+		configDir:           configDir,
+		store:               store,
+		changeCallbacks:     make([]StatusChangeCallback, 0),
+		transcriptParser:    claude.NewTranscriptParser(),
+		lastMessages:        make(map[string]string),
+		fullLastMessages:    make(map[string]string),
+		unsavedLastMessages: make(map[string]bool),
 	}
-	
+
 	// Only set up watchers if not in hook mode
 	if !hookMode {
+		// Restore last messages persisted by a previous run
+		if lastMessages, err := store.GetLastMessages(); err != nil {
+			log.Printf("Failed to load last messages: %v", err)
+		} else {
+			for key, lastMessage := range lastMessages {
+				manager.lastMessages[key] = lastMessage.Message
+				manager.fullLastMessages[key] = lastMessage.FullMessage
+			}
+		}
+
 		fileWatcher := &FileWatcher{
-			statusFile: statusFile,
-			manager:    manager,
-			stopCh:     make(chan bool),
+			manager: manager,
+			stopCh:  make(chan bool),
 		}
 		manager.fileWatcher = fileWatcher
-		
+
 		// Set up transcript watcher
-		transcriptWatcher := NewTranscriptWatcher(manager, statusFile)
+		transcriptWatcher := NewTranscriptWatcher(manager)
 		manager.transcriptWatcher = transcriptWatcher
-		
+
 		// Start file watching in a goroutine
 		go fileWatcher.start()
-		
+
+		// Keep status history within the configured retention; stops with the file watcher
+		go manager.pruneStatusHistoryLoop(fileWatcher.stopCh)
+
+		// Notice Claude Code processes that crashed or were closed without a SessionEnd
+		// hook, and approval hooks that went away without an answer
+		go manager.reapLoop(fileWatcher.stopCh)
+
+		// Send scheduled minion messages as they come due
+		go manager.runSchedulesLoop(fileWatcher.stopCh)
+
 		// Start transcript watching
 		if err := transcriptWatcher.Start(); err != nil {
 			return nil, fmt.Errorf("failed to start transcript watcher: %w", err)
 		}
-		
+
 		log.Printf("Started file and transcript watchers (non-hook mode)")
 	} else {
 		log.Printf("Skipping watchers initialization (hook mode)")
 	}
-	
+
 	return manager, nil
 }
 
+// ConfigDir returns the directory holding the dashboard's state files
+func (m *Manager) ConfigDir() string {
+	return m.configDir
+}
+
 // NewTranscriptWatcher creates a new TranscriptWatcher instance
-func NewTranscriptWatcher(manager *Manager, agentStatusPath string) *TranscriptWatcher {
+func NewTranscriptWatcher(manager *Manager) *TranscriptWatcher {
 	return &TranscriptWatcher{
 		manager:            manager,
-		agentStatusPath:    agentStatusPath,
 		transcriptWatchers: make(map[string]*fsnotify.Watcher),
-		projectToSession:   make(map[string]string),
 		knownRepos:         make(map[string]bool),
 		stopCh:             make(chan bool),
 	}
 }
 
-// Start begins the transcript watching system
+// Start begins the transcript watching system. Agent status changes are fed in
+// by the FileWatcher, which polls the store.
 func (tw *TranscriptWatcher) Start() error {
-	// Create watcher for agent status file
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("failed to create fsnotify watcher: %w", err)
-	}
-	tw.agentWatcher = watcher
-	
-	// Add agent status file to watch
-	if err := tw.agentWatcher.Add(tw.agentStatusPath); err != nil {
-		return fmt.Errorf("failed to watch agent status file: %w", err)
-	}
-	
 	// Initialize known repositories
 	tw.updateKnownRepos()
-	
-	// Start the main watching loop
-	go tw.watchLoop()
-	
+
 	// Initial agent status check to set up any existing watchers
 	tw.handleAgentStatusChange()
-	
+
 	log.Println("Started transcript watcher with fsnotify")
 	return nil
 }
@@ -137,95 +144,69 @@ func (tw *TranscriptWatcher) Start() error {
 // Stop stops the transcript watching system
 func (tw *TranscriptWatcher) Stop() {
 	close(tw.stopCh)
-	
-	// Close agent status watcher
-	if tw.agentWatcher != nil {
-		tw.agentWatcher.Close()
-	}
-	
+
 	// Close all transcript watchers
 	tw.mutex.Lock()
 	for sessionID, watcher := range tw.transcriptWatchers {
 		watcher.Close()
 		delete(tw.transcriptWatchers, sessionID)
 	}
-	// Clear project to session mapping
-	tw.projectToSession = make(map[string]string)
 	tw.mutex.Unlock()
-	
-	log.Println("Stopped transcript watcher")
-}
 
-// watchLoop is the main event loop for the transcript watcher
-func (tw *TranscriptWatcher) watchLoop() {
-	for {
-		select {
-		case event, ok := <-tw.agentWatcher.Events:
-			if !ok {
-				return
-			}
-			
-			if event.Op&fsnotify.Write == fsnotify.Write {
-				log.Printf("Agent status file changed: %s", event.Name)
-				tw.handleAgentStatusChange()
-			}
-		case err, ok := <-tw.agentWatcher.Errors:
-			if !ok {
-				return
-			}
-			log.Printf("Agent status watcher error: %v", err)
-		case <-tw.stopCh:
-			return
-		}
-	}
+	log.Println("Stopped transcript watcher")
 }
 
-// handleAgentStatusChange processes changes to the agent status file
+// handleAgentStatusChange processes changes to the agent statuses
 func (tw *TranscriptWatcher) handleAgentStatusChange() {
 	// Update known repositories
 	tw.updateKnownRepos()
-	
+
 	// Read current agent status
 	statuses, err := tw.manager.GetAgentStatus()
 	if err != nil {
 		log.Printf("Failed to read agent status: %v", err)
 		return
 	}
-	
+
 	log.Printf("Processing agent status change - found %d agent statuses", len(statuses))
-	
+
 	// Track which sessions should be watched
 	activeSessionIDs := make(map[string]bool)
-	
+
 	// Process each agent status entry
 	for _, status := range statuses {
-		log.Printf("Checking agent: path=%s, sessionID=%s, transcriptPath=%s, status=%s", 
+		log.Printf("Checking agent: path=%s, sessionID=%s, transcriptPath=%s, status=%s",
 			status.Path, status.SessionID, status.TranscriptPath, status.Status)
-		
+
 		// Only monitor agents in known repositories
 		if !tw.isKnownRepository(status.Path) {
 			log.Printf("Skipping agent - path %s not in known repositories", status.Path)
 			continue
 		}
-		
+
+		// Finished sessions won't write to their transcripts again
+		if status.Finished() {
+			continue
+		}
+
 		var sessionID, transcriptPath string
-		
-		// If session/transcript info is missing, try to auto-discover
+
+		// Hooks report each session's transcript; older entries without one are auto-discovered
 		if status.SessionID == "" || status.TranscriptPath == "" {
 			log.Printf("Auto-discovering transcript for path: %s", status.Path)
-			
+
 			// Use the transcript parser to find the most recent transcript
 			transcriptInfo, err := tw.manager.transcriptParser.FindMostRecentTranscript(status.Path)
 			if err != nil {
 				log.Printf("Failed to auto-discover transcript for %s: %v", status.Path, err)
 				continue
 			}
-			
+
 			if transcriptInfo == nil {
 				log.Printf("No transcript found for path: %s", status.Path)
 				continue
 			}
-			
+
 			sessionID = transcriptInfo.SessionID
 			transcriptPath = transcriptInfo.Path
 			log.Printf("Auto-discovered transcript: session=%s, path=%s", sessionID, transcriptPath)
@@ -233,28 +214,14 @@ func (tw *TranscriptWatcher) handleAgentStatusChange() {
 			sessionID = status.SessionID
 			transcriptPath = status.TranscriptPath
 		}
-		
+
 		activeSessionIDs[sessionID] = true
-		
-		// Check if we need to stop watching an old session for this project
-		tw.mutex.Lock()
-		if oldSessionID, exists := tw.projectToSession[status.Path]; exists && oldSessionID != sessionID {
-			log.Printf("Stopping old transcript watcher for project %s (old session: %s, new session: %s)", status.Path, oldSessionID, sessionID)
-			if oldWatcher, exists := tw.transcriptWatchers[oldSessionID]; exists {
-				oldWatcher.Close()
-				delete(tw.transcriptWatchers, oldSessionID)
-			}
-		}
-		
-		// Update project to session mapping
-		tw.projectToSession[status.Path] = sessionID
-		tw.mutex.Unlock()
-		
+
 		// Add watcher if not already watching
 		tw.mutex.RLock()
 		_, exists := tw.transcriptWatchers[sessionID]
 		tw.mutex.RUnlock()
-		
+
 		if !exists {
 			log.Printf("Adding new transcript watcher for session %s", sessionID)
 			tw.addTranscriptWatcher(sessionID, transcriptPath)
@@ -262,7 +229,7 @@ func (tw *TranscriptWatcher) handleAgentStatusChange() {
 			log.Printf("Already watching transcript for session %s", sessionID)
 		}
 	}
-	
+
 	// Remove watchers for sessions that are no longer active
 	tw.mutex.Lock()
 	for sessionID, watcher := range tw.transcriptWatchers {
@@ -270,21 +237,13 @@ func (tw *TranscriptWatcher) handleAgentStatusChange() {
 			log.Printf("Removing transcript watcher for inactive session: %s", sessionID)
 			watcher.Close()
 			delete(tw.transcriptWatchers, sessionID)
-			
-			// Also remove from project to session mapping
-			for projectPath, mappedSessionID := range tw.projectToSession {
-				if mappedSessionID == sessionID {
-					delete(tw.projectToSession, projectPath)
-					break
-				}
-			}
 		}
 	}
 	tw.mutex.Unlock()
-	
+
 	// Correct status based on transcript analysis (startup detection)
 	tw.correctStatusFromTranscripts()
-	
+
 	// Debug: Show current watched transcripts
 	tw.DebugWatchedTranscripts()
 }
@@ -296,27 +255,27 @@ func (tw *TranscriptWatcher) addTranscriptWatcher(sessionID, transcriptPath stri
 		log.Printf("Transcript file does not exist: %s", transcriptPath)
 		return
 	}
-	
+
 	// Create watcher for this transcript
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Printf("Failed to create watcher for transcript %s: %v", transcriptPath, err)
 		return
 	}
-	
+
 	// Add transcript file to watch
 	if err := watcher.Add(transcriptPath); err != nil {
 		log.Printf("Failed to watch transcript file %s: %v", transcriptPath, err)
 		watcher.Close()
 		return
 	}
-	
+
 	tw.mutex.Lock()
 	tw.transcriptWatchers[sessionID] = watcher
 	tw.mutex.Unlock()
-	
+
 	log.Printf("Added transcript watcher for session %s: %s", sessionID, transcriptPath)
-	
+
 	// Start watching this transcript in a goroutine
 	go tw.watchTranscript(sessionID, transcriptPath, watcher)
 }
@@ -329,10 +288,10 @@ func (tw *TranscriptWatcher) watchTranscript(sessionID, transcriptPath string, w
 			if !ok {
 				return
 			}
-			
+
 			if event.Op&fsnotify.Write == fsnotify.Write {
 				log.Printf("Transcript file changed: %s", event.Name)
-				tw.handleTranscriptChange(transcriptPath)
+				tw.handleTranscriptChange(sessionID, transcriptPath)
 			}
 		case err, ok := <-watcher.Errors:
 			if !ok {
@@ -345,61 +304,58 @@ func (tw *TranscriptWatcher) watchTranscript(sessionID, transcriptPath string, w
 	}
 }
 
-// handleTranscriptChange processes changes to a transcript file
-func (tw *TranscriptWatcher) handleTranscriptChange(transcriptPath string) {
-	// Find the agent status entry by extracting project path from transcript path
-	statuses, err := tw.manager.GetAgentStatus()
+// handleTranscriptChange processes changes to a session's transcript file
+func (tw *TranscriptWatcher) handleTranscriptChange(sessionID, transcriptPath string) {
+	cause := TransitionCause{Source: CauseTranscript, Event: "message", SessionID: sessionID}
+	err := tw.manager.UpdateAgentStatus(cause, func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		return statuses, tw.applyTranscriptChange(statuses, sessionID, transcriptPath)
+	})
 	if err != nil {
-		log.Printf("Failed to get agent status: %v", err)
-		return
+		log.Printf("Failed to update agent status from transcript: %v", err)
 	}
-	
+}
+
+// applyTranscriptChange updates the status entry for a transcript's session in place,
+// reporting whether anything should be saved
+func (tw *TranscriptWatcher) applyTranscriptChange(statuses []AgentStatus, sessionID, transcriptPath string) bool {
 	var targetStatus *AgentStatus
 	var statusIndex int
-	
-	// Extract project path from transcript path
-	// Example: ~/.claude/projects/-home-mrdon-dev-sleuth-minions/session.jsonl -> /home/mrdon/dev/sleuth-minions
-	projectPath := tw.extractProjectPathFromTranscript(transcriptPath)
-	if projectPath == "" {
-		log.Printf("Could not extract project path from transcript: %s", transcriptPath)
-		return
-	}
-	
+
 	for i, status := range statuses {
-		if status.Path == projectPath {
+		if status.SessionID == sessionID {
 			targetStatus = &statuses[i]
 			statusIndex = i
 			break
 		}
 	}
-	
 	if targetStatus == nil {
-		log.Printf("No agent status found for project path: %s (transcript: %s)", projectPath, transcriptPath)
-		return
+		targetStatus, statusIndex = tw.findLegacyStatus(statuses, transcriptPath)
+	}
+
+	if targetStatus == nil {
+		log.Printf("No agent status found for session %s (transcript: %s)", sessionID, transcriptPath)
+		return false
 	}
-	
+
 	// Extract latest message using transcript parser
 	lastMessage, err := tw.manager.transcriptParser.GetLastMessage(transcriptPath)
 	if err != nil {
 		log.Printf("Failed to get last message from transcript %s: %v", transcriptPath, err)
-		return
+		return false
 	}
-	
+
 	fullMessage, err := tw.manager.transcriptParser.GetLastMessageFull(transcriptPath)
 	if err != nil {
 		log.Printf("Failed to get full last message from transcript %s: %v", transcriptPath, err)
 		fullMessage = lastMessage // fallback
 	}
-	
-	// Store messages in memory instead of JSON file
-	tw.manager.messagesMutex.Lock()
-	tw.manager.lastMessages[targetStatus.Path] = lastMessage
-	tw.manager.fullLastMessages[targetStatus.Path] = fullMessage
-	tw.manager.messagesMutex.Unlock()
-	
+
+	// Cache messages in memory; they are persisted once the status update completes
+	tw.manager.setLastMessages(targetStatus.Key(), lastMessage, fullMessage)
+
 	// Update agent status (without messages)
 	statuses[statusIndex].LastActivity = time.Now()
-	
+
 	// Check if we should change status to "running"
 	// If status is "waiting", check if the last message is newer than when status was set to waiting
 	shouldChangeToRunning := false
@@ -412,17 +368,17 @@ func (tw *TranscriptWatcher) handleTranscriptChange(transcriptPath string) {
 				shouldChangeToRunning = true // fallback to old behavior
 			} else if lastMessageRole == "user" {
 				// If the most recent message is from user, always change to running (new conversation starting)
-				log.Printf("Status is waiting but most recent message is from user (%v), changing to running for path: %s", 
+				log.Printf("Status is waiting but most recent message is from user (%v), changing to running for path: %s",
 					lastMessageTime, targetStatus.Path)
 				shouldChangeToRunning = true
 			} else if lastMessageTime.Before(targetStatus.LastActivity) {
 				// Assistant message that's older than last activity - don't change status
-				log.Printf("Status is waiting and last message (%v, %s) is before last activity (%v), not changing status for path: %s", 
+				log.Printf("Status is waiting and last message (%v, %s) is before last activity (%v), not changing status for path: %s",
 					lastMessageTime, lastMessageRole, targetStatus.LastActivity, targetStatus.Path)
 				shouldChangeToRunning = false
 			} else {
 				// Assistant message that's newer than last activity - change to running
-				log.Printf("Status is waiting but last message (%v, %s) is after last activity (%v), changing to running for path: %s", 
+				log.Printf("Status is waiting but last message (%v, %s) is after last activity (%v), changing to running for path: %s",
 					lastMessageTime, lastMessageRole, targetStatus.LastActivity, targetStatus.Path)
 				shouldChangeToRunning = true
 			}
@@ -434,64 +390,75 @@ func (tw *TranscriptWatcher) handleTranscriptChange(transcriptPath string) {
 	} else {
 		log.Printf("Message appears to be system output, not changing status %s for path: %s (message: %.50s...)", targetStatus.Status, targetStatus.Path, lastMessage)
 	}
-	
+
 	if shouldChangeToRunning {
 		oldStatus := targetStatus.Status
 		newStatus := "running"
-		log.Printf("STATUS CHANGE: %s -> %s for path: %s (reason: conversation message detected: %.50s...)", 
+		log.Printf("STATUS CHANGE: %s -> %s for path: %s (reason: conversation message detected: %.50s...)",
 			oldStatus, newStatus, targetStatus.Path, lastMessage)
 		statuses[statusIndex].Status = newStatus
 	}
-	
+
 	// Save updated status (without race condition from messages)
-	if err := tw.manager.SaveAgentStatus(statuses); err != nil {
-		log.Printf("Failed to save updated agent status: %v", err)
+	return true
+}
+
+// findLegacyStatus finds the path-keyed status entry, recorded before sessions were
+// tracked, for the project a transcript belongs to
+func (tw *TranscriptWatcher) findLegacyStatus(statuses []AgentStatus, transcriptPath string) (*AgentStatus, int) {
+	// Example: ~/.claude/projects/-home-mrdon-dev-sleuth-minions/session.jsonl -> /home/mrdon/dev/sleuth-minions
+	projectPath := extractProjectPathFromTranscript(statuses, transcriptPath)
+	if projectPath == "" {
+		log.Printf("Could not extract project path from transcript: %s", transcriptPath)
+		return nil, -1
+	}
+
+	for i, status := range statuses {
+		if status.Path == projectPath && status.SessionID == "" {
+			return &statuses[i], i
+		}
 	}
+	return nil, -1
 }
 
 // extractProjectPathFromTranscript extracts the original project path from a transcript file path
 // Example: ~/.claude/projects/-home-mrdon-dev-sleuth-minions/session.jsonl -> /home/mrdon/dev/sleuth-minions
-func (tw *TranscriptWatcher) extractProjectPathFromTranscript(transcriptPath string) string {
+func extractProjectPathFromTranscript(statuses []AgentStatus, transcriptPath string) string {
 	// Get the directory containing the transcript file
 	dir := filepath.Dir(transcriptPath)
-	
+
 	// Extract the project directory name (last part of path)
 	projectDirName := filepath.Base(dir)
-	
+
 	// Convert back from Claude's format: -home-mrdon-dev-sleuth-minions -> /home/mrdon/dev/sleuth-minions
 	if !strings.HasPrefix(projectDirName, "-") {
 		return ""
 	}
-	
+
 	// Remove the leading dash
 	projectPath := strings.TrimPrefix(projectDirName, "-")
-	
+
 	// We need to be smarter about converting dashes back to slashes
 	// The original FindMostRecentTranscript function uses: strings.ReplaceAll(projectPath, "/", "-")
 	// So we need to reverse this carefully
 	// Split by dashes and reconstruct, but we need to handle the case where
 	// directory names themselves contain dashes
-	
+
 	// For now, let's try a different approach - check against known agent statuses
-	statuses, err := tw.manager.GetAgentStatus()
-	if err != nil {
-		return ""
-	}
-	
 	// Try to find a matching agent status by checking if any path would generate this project dir name
 	for _, status := range statuses {
 		// Convert the status path to Claude's directory format
 		// Example: /home/mrdon/dev/sleuth -> -home-mrdon-dev-sleuth
 		expectedDirName := strings.ReplaceAll(status.Path, "/", "-")
-		
+
 		log.Printf("Comparing transcript dir '%s' with expected dir '%s' for path '%s'", projectPath, expectedDirName, status.Path)
-		
+
 		// The transcript directory name has the leading dash removed, so we need to add it back for comparison
 		if expectedDirName == "-"+projectPath {
 			return status.Path
 		}
 	}
-	
+
 	return ""
 }
 
@@ -502,14 +469,15 @@ func (tw *TranscriptWatcher) updateKnownRepos() {
 		log.Printf("Failed to get repositories: %v", err)
 		return
 	}
-	
+
 	tw.mutex.Lock()
 	tw.knownRepos = make(map[string]bool)
 	for _, repo := range repos {
-		tw.knownRepos[repo.Path] = true
+		// Statuses carry canonical paths, whatever path the repository was added with
+		tw.knownRepos[fsutil.CanonicalPath(repo.Path)] = true
 	}
 	tw.mutex.Unlock()
-	
+
 	log.Printf("Updated known repositories: %v", tw.getKnownReposList())
 }
 
@@ -517,7 +485,7 @@ func (tw *TranscriptWatcher) updateKnownRepos() {
 func (tw *TranscriptWatcher) getKnownReposList() []string {
 	tw.mutex.RLock()
 	defer tw.mutex.RUnlock()
-	
+
 	var paths []string
 	for path := range tw.knownRepos {
 		paths = append(paths, path)
@@ -529,21 +497,21 @@ func (tw *TranscriptWatcher) getKnownReposList() []string {
 func (tw *TranscriptWatcher) DebugWatchedTranscripts() {
 	tw.mutex.RLock()
 	defer tw.mutex.RUnlock()
-	
+
 	log.Printf("Currently watching %d transcript files:", len(tw.transcriptWatchers))
-	
+
 	// Get current agent statuses to find transcript paths and repo paths
 	statuses, err := tw.manager.GetAgentStatus()
 	if err != nil {
 		log.Printf("Failed to get agent status for debug: %v", err)
 		return
 	}
-	
+
 	for sessionID := range tw.transcriptWatchers {
 		// Find the corresponding agent status for this session
 		for _, status := range statuses {
 			if status.SessionID == sessionID {
-				log.Printf("  - Session: %s, Repo: %s, Transcript: %s", 
+				log.Printf("  - Session: %s, Repo: %s, Transcript: %s",
 					sessionID, status.Path, status.TranscriptPath)
 				break
 			}
@@ -553,157 +521,101 @@ func (tw *TranscriptWatcher) DebugWatchedTranscripts() {
 
 // isKnownRepository checks if a path is a known repository
 func (tw *TranscriptWatcher) isKnownRepository(path string) bool {
+	path = fsutil.CanonicalPath(path)
+
 	tw.mutex.RLock()
 	defer tw.mutex.RUnlock()
-	
+
 	// Check exact match
 	if tw.knownRepos[path] {
 		return true
 	}
-	
+
 	// Check if path is a subdirectory of any known repo
 	for repoPath := range tw.knownRepos {
 		if strings.HasPrefix(path, repoPath+"/") {
 			return true
 		}
 	}
-	
+
 	return false
 }
 
-
-
-
 func (m *Manager) GetRepositories() ([]Repository, error) {
-	repoFile := filepath.Join(m.configDir, "repositories.json")
-	
-	if _, err := os.Stat(repoFile); os.IsNotExist(err) {
-		fmt.Printf("Repository file does not exist: %s\n", repoFile)
-		return []Repository{}, nil
-	}
-	
-	data, err := os.ReadFile(repoFile)
+	return m.store.GetRepositories()
+}
+
+// GetRepository returns a registered repository by ID
+func (m *Manager) GetRepository(id string) (*Repository, error) {
+	repos, err := m.store.GetRepositories()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read repositories file: %w", err)
+		return nil, err
 	}
-	
-	var repos []Repository
-	if err := json.Unmarshal(data, &repos); err != nil {
-		return nil, fmt.Errorf("failed to parse repositories file: %w", err)
+	for _, repo := range repos {
+		if repo.ID == id {
+			return &repo, nil
+		}
 	}
-	
-	return repos, nil
+	return nil, fmt.Errorf("repository %w: %s", ErrNotFound, id)
 }
 
 func (m *Manager) SaveRepositories(repos []Repository) error {
-	repoFile := filepath.Join(m.configDir, "repositories.json")
-	
-	data, err := json.MarshalIndent(repos, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal repositories: %w", err)
-	}
-	
-	fmt.Printf("Saving repositories to: %s\n", repoFile)
-	if err := os.WriteFile(repoFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write repositories file: %w", err)
-	}
-	
-	fmt.Printf("Successfully saved %d repositories\n", len(repos))
-	return nil
+	return m.store.SaveRepositories(repos)
 }
 
 func (m *Manager) AddRepository(path, name string) (*Repository, error) {
-	repos, err := m.GetRepositories()
-	if err != nil {
-		return nil, err
-	}
-	
-	// Check if repository already exists
-	for _, repo := range repos {
-		if repo.Path == path {
-			return nil, fmt.Errorf("repository already exists: %s", path)
+	var newRepo Repository
+	err := m.store.UpdateRepositories(func(repos []Repository) ([]Repository, error) {
+		// Check if repository already exists
+		for _, repo := range repos {
+			if fsutil.CanonicalPath(repo.Path) == fsutil.CanonicalPath(path) {
+				return nil, fmt.Errorf("repository %w: %s", ErrExists, path)
+			}
 		}
-	}
-	
-	// Generate ID
-	id := fmt.Sprintf("repo_%d", time.Now().Unix())
-	
-	newRepo := Repository{
-		ID:        id,
-		Path:      path,
-		Name:      name,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	
-	repos = append(repos, newRepo)
-	
-	if err := m.SaveRepositories(repos); err != nil {
+
+		// Generate ID
+		id := fmt.Sprintf("repo_%d", time.Now().Unix())
+
+		newRepo = Repository{
+			ID:        id,
+			Path:      path,
+			Name:      name,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+
+		return append(repos, newRepo), nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	
+
 	return &newRepo, nil
 }
 
 func (m *Manager) RemoveRepository(id string) error {
-	repos, err := m.GetRepositories()
-	if err != nil {
-		return err
-	}
-	
-	// Filter out the repository
-	var filteredRepos []Repository
-	found := false
-	for _, repo := range repos {
-		if repo.ID != id {
-			filteredRepos = append(filteredRepos, repo)
-		} else {
-			found = true
+	return m.store.UpdateRepositories(func(repos []Repository) ([]Repository, error) {
+		// Filter out the repository
+		var filteredRepos []Repository
+		found := false
+		for _, repo := range repos {
+			if repo.ID != id {
+				filteredRepos = append(filteredRepos, repo)
+			} else {
+				found = true
+			}
 		}
-	}
-	
-	if !found {
-		return fmt.Errorf("repository not found: %s", id)
-	}
-	
-	return m.SaveRepositories(filteredRepos)
+
+		if !found {
+			return nil, fmt.Errorf("repository %w: %s", ErrNotFound, id)
+		}
+
+		return filteredRepos, nil
+	})
 }
 
 func (m *Manager) GetAgentStatus() ([]AgentStatus, error) {
-	statusFile := filepath.Join(m.configDir, "agent-status.json")
-	
-	if _, err := os.Stat(statusFile); os.IsNotExist(err) {
-		return []AgentStatus{}, nil
-	}
-	
-	data, err := os.ReadFile(statusFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read agent status file: %w", err)
-	}
-	
-	var statuses []AgentStatus
-	if err := json.Unmarshal(data, &statuses); err != nil {
-		log.Printf("Agent status JSON is corrupted, attempting recovery: %v", err)
-		
-		// Try to recover from common corruption patterns
-		recoveredStatuses, recoveryErr := m.recoverCorruptedAgentStatus(data)
-		if recoveryErr != nil {
-			// If recovery fails, start fresh
-			log.Printf("Recovery failed, starting with empty status: %v", recoveryErr)
-			return []AgentStatus{}, nil
-		}
-		
-		log.Printf("Successfully recovered %d agent statuses", len(recoveredStatuses))
-		
-		// Save the recovered data
-		if saveErr := m.SaveAgentStatus(recoveredStatuses); saveErr != nil {
-			log.Printf("Failed to save recovered agent status: %v", saveErr)
-		}
-		
-		return recoveredStatuses, nil
-	}
-	
-	return statuses, nil
+	return m.store.GetAgentStatus()
 }
 
 // GetAgentStatusWithMessages returns agent statuses with last messages from memory
@@ -712,89 +624,107 @@ func (m *Manager) GetAgentStatusWithMessages() ([]AgentStatusWithMessages, error
 	if err != nil {
 		return nil, err
 	}
-	
+
 	m.messagesMutex.RLock()
 	defer m.messagesMutex.RUnlock()
-	
+
 	var statusesWithMessages []AgentStatusWithMessages
 	for _, status := range statuses {
 		statusWithMessages := AgentStatusWithMessages{
 			AgentStatus:     status,
-			LastMessage:     m.lastMessages[status.Path],
-			FullLastMessage: m.fullLastMessages[status.Path],
+			LastMessage:     m.lastMessages[status.Key()],
+			FullLastMessage: m.fullLastMessages[status.Key()],
 		}
 		statusesWithMessages = append(statusesWithMessages, statusWithMessages)
 	}
-	
+
 	return statusesWithMessages, nil
 }
 
 // correctStatusFromTranscripts analyzes transcripts to correct status on startup
 func (tw *TranscriptWatcher) correctStatusFromTranscripts() {
-	statuses, err := tw.manager.GetAgentStatus()
+	var hasStatusChanges bool
+	cause := TransitionCause{Source: CauseTranscript, Event: "correction"}
+	err := tw.manager.UpdateAgentStatus(cause, func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		hasStatusChanges = tw.applyTranscriptCorrections(statuses)
+		return statuses, hasStatusChanges
+	})
 	if err != nil {
-		log.Printf("Failed to get agent status for transcript analysis: %v", err)
-		return
+		log.Printf("Failed to save corrected agent status: %v", err)
+	} else if hasStatusChanges {
+		log.Printf("Saved corrected agent statuses based on transcript analysis")
 	}
-	
+}
+
+// applyTranscriptCorrections sets each known agent's status from its transcript,
+// reporting whether any status changed
+func (tw *TranscriptWatcher) applyTranscriptCorrections(statuses []AgentStatus) bool {
 	var hasStatusChanges bool
-	
+
 	for i, status := range statuses {
 		// Only analyze agents in known repositories
 		if !tw.isKnownRepository(status.Path) {
 			continue
 		}
-		
-		// Try to find the transcript for this agent
-		transcriptInfo, err := tw.manager.transcriptParser.FindMostRecentTranscript(status.Path)
-		if err != nil {
-			log.Printf("Failed to find transcript for %s: %v", status.Path, err)
+
+		// Lifecycle statuses come straight from hook events and can't be inferred from a transcript
+		if status.Finished() || status.Status == "compacting" {
 			continue
 		}
-		
-		if transcriptInfo == nil {
-			log.Printf("No transcript found for %s, keeping status as %s", status.Path, status.Status)
-			continue
+
+		// Sessions know their transcript; older entries fall back to the most recent one for the path
+		transcriptPath := status.TranscriptPath
+		if transcriptPath == "" {
+			transcriptInfo, err := tw.manager.transcriptParser.FindMostRecentTranscript(status.Path)
+			if err != nil {
+				log.Printf("Failed to find transcript for %s: %v", status.Path, err)
+				continue
+			}
+
+			if transcriptInfo == nil {
+				log.Printf("No transcript found for %s, keeping status as %s", status.Path, status.Status)
+				continue
+			}
+			transcriptPath = transcriptInfo.Path
 		}
-		
+
 		// Analyze the transcript to determine correct status
-		detectedStatus := tw.manager.transcriptParser.DetermineSessionStatus(transcriptInfo.Path)
-		
+		detectedStatus := tw.manager.transcriptParser.DetermineSessionStatus(transcriptPath)
+
 		if detectedStatus != status.Status {
-			log.Printf("STARTUP STATUS CORRECTION: %s -> %s for path: %s (transcript analysis)", 
+			log.Printf("STARTUP STATUS CORRECTION: %s -> %s for path: %s (transcript analysis)",
 				status.Status, detectedStatus, status.Path)
 			statuses[i].Status = detectedStatus
 			hasStatusChanges = true
 		} else {
 			log.Printf("Status %s confirmed correct for path: %s (transcript analysis)", status.Status, status.Path)
 		}
-		
+
 		// Set the last message from the transcript during startup
-		if lastMessage, err := tw.manager.transcriptParser.GetLastMessage(transcriptInfo.Path); err == nil && lastMessage != "" {
-			tw.manager.messagesMutex.Lock()
-			tw.manager.lastMessages[status.Path] = lastMessage
+		if lastMessage, err := tw.manager.transcriptParser.GetLastMessage(transcriptPath); err == nil && lastMessage != "" {
 			// Also get the full message
-			if fullMessage, err := tw.manager.transcriptParser.GetLastMessageFull(transcriptInfo.Path); err == nil && fullMessage != "" {
-				tw.manager.fullLastMessages[status.Path] = fullMessage
+			fullMessage, err := tw.manager.transcriptParser.GetLastMessageFull(transcriptPath)
+			if err != nil || fullMessage == "" {
+				tw.manager.messagesMutex.RLock()
+				fullMessage = tw.manager.fullLastMessages[status.Key()]
+				tw.manager.messagesMutex.RUnlock()
 			}
-			tw.manager.messagesMutex.Unlock()
-		}
-	}
-	
-	// Save updated statuses if any changes were made
-	if hasStatusChanges {
-		if err := tw.manager.SaveAgentStatus(statuses); err != nil {
-			log.Printf("Failed to save corrected agent status: %v", err)
-		} else {
-			log.Printf("Saved corrected agent statuses based on transcript analysis")
+			tw.manager.setLastMessages(status.Key(), lastMessage, fullMessage)
 		}
 	}
+
+	return hasStatusChanges
 }
 
 func (m *Manager) AddStatusChangeCallback(callback StatusChangeCallback) {
 	m.changeCallbacks = append(m.changeCallbacks, callback)
 }
 
+// SetMinionMessageDeliverer registers a fast path for delivering minion messages
+func (m *Manager) SetMinionMessageDeliverer(deliverer MinionMessageDeliverer) {
+	m.deliverMessage = deliverer
+}
+
 func (m *Manager) Close() {
 	if m.fileWatcher != nil {
 		m.fileWatcher.stop()
@@ -802,6 +732,9 @@ func (m *Manager) Close() {
 	if m.transcriptWatcher != nil {
 		m.transcriptWatcher.Stop()
 	}
+	if err := m.store.Close(); err != nil {
+		log.Printf("Failed to close state store: %v", err)
+	}
 }
 
 func (m *Manager) notifyStatusChange() {
@@ -811,177 +744,167 @@ func (m *Manager) notifyStatusChange() {
 }
 
 func (m *Manager) SaveAgentStatus(statuses []AgentStatus) error {
-	statusFile := filepath.Join(m.configDir, "agent-status.json")
-	
-	data, err := json.MarshalIndent(statuses, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal agent status: %w", err)
-	}
-	
-	if err := os.WriteFile(statusFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write agent status file: %w", err)
+	if err := m.store.SaveAgentStatus(statuses); err != nil {
+		return err
 	}
-	
+
 	// Notify callbacks that status has changed
 	m.notifyStatusChange()
-	
-	return nil
-}
 
-func (m *Manager) GetLastTranscriptMessage(transcriptPath string) (string, error) {
-	if transcriptPath == "" {
-		return "", nil
-	}
-	return m.transcriptParser.GetLastMessage(transcriptPath)
+	return nil
 }
 
+// UpdateAgentStatus runs an atomic read-modify-write of the agent statuses, so
+// concurrent hook processes never lose each other's updates. The statuses are
+// saved only when update reports a change, and every status that changed is
+// recorded in the transition history with the given cause.
+func (m *Manager) UpdateAgentStatus(cause TransitionCause, update func(statuses []AgentStatus) ([]AgentStatus, bool)) error {
+	changed := false
+	var transitions []StatusTransition
+	err := m.store.UpdateAgentStatus(func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		oldStatuses := make(map[string]string, len(statuses))
+		for _, status := range statuses {
+			oldStatuses[status.Key()] = status.Status
+		}
 
-
-func (m *Manager) GetLastTranscriptMessageFull(transcriptPath string) (string, error) {
-	if transcriptPath == "" {
-		return "", nil
-	}
-	return m.transcriptParser.GetLastMessageFull(transcriptPath)
-}
-
-func (w *FileWatcher) start() {
-	var lastModTime time.Time
-	
-	// Get initial modification time
-	if stat, err := os.Stat(w.statusFile); err == nil {
-		lastModTime = stat.ModTime()
-	}
-	
-	ticker := time.NewTicker(1 * time.Second) // Check every second
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-w.stopCh:
-			return
-		case <-ticker.C:
-			if stat, err := os.Stat(w.statusFile); err == nil {
-				if stat.ModTime().After(lastModTime) {
-					lastModTime = stat.ModTime()
-					log.Printf("Agent status file changed, notifying callbacks")
-					w.manager.notifyStatusChange()
-				}
-			}
+		statuses, changed = update(statuses)
+		if changed {
+			transitions = diffStatuses(oldStatuses, statuses, cause)
 		}
-	}
-}
+		return statuses, changed
+	})
 
-func (w *FileWatcher) stop() {
-	close(w.stopCh)
-}
+	// Persist messages cached during the update now that the store is free again
+	m.flushLastMessages()
 
-// AddMinionMessage adds a message for a minion in a specific directory
-func (m *Manager) AddMinionMessage(path, message string) error {
-	messages, err := m.GetMinionMessages(path)
 	if err != nil {
 		return err
 	}
 
-	newMessage := MinionMessage{
-		ID:        fmt.Sprintf("msg_%d", time.Now().UnixNano()),
-		Path:      path,
-		Message:   message,
-		Timestamp: time.Now(),
+	if len(transitions) > 0 {
+		if err := m.store.AddStatusTransitions(transitions); err != nil {
+			log.Printf("Failed to record status transitions: %v", err)
+		}
 	}
 
-	messages = append(messages, newMessage)
-	return m.saveMinionMessages(path, messages)
+	if changed {
+		m.notifyStatusChange()
+	}
+	return nil
 }
 
-// GetMinionMessages gets all pending messages for a minion in a specific directory
-func (m *Manager) GetMinionMessages(path string) ([]MinionMessage, error) {
-	messageFile := m.getMinionMessageFile(path)
-	
-	if _, err := os.Stat(messageFile); os.IsNotExist(err) {
-		return []MinionMessage{}, nil
-	}
+// diffStatuses returns a transition for every session whose status differs from before
+func diffStatuses(oldStatuses map[string]string, statuses []AgentStatus, cause TransitionCause) []StatusTransition {
+	var transitions []StatusTransition
+	now := time.Now()
 
-	data, err := os.ReadFile(messageFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read minion messages file: %w", err)
-	}
+	for _, status := range statuses {
+		oldStatus, existed := oldStatuses[status.Key()]
+		if existed && oldStatus == status.Status {
+			continue
+		}
+
+		// Other entries can change in the same update, so the trigger's session only
+		// stands in for a legacy entry without one in the trigger's own directory
+		sessionID := status.SessionID
+		if sessionID == "" && cause.Path != "" && status.Path == cause.Path {
+			sessionID = cause.SessionID
+		}
 
-	var messages []MinionMessage
-	if err := json.Unmarshal(data, &messages); err != nil {
-		return nil, fmt.Errorf("failed to parse minion messages file: %w", err)
+		transitions = append(transitions, StatusTransition{
+			ID:        fmt.Sprintf("transition_%d_%d", now.UnixNano(), len(transitions)),
+			Path:      status.Path,
+			SessionID: sessionID,
+			OldStatus: oldStatus,
+			NewStatus: status.Status,
+			Cause:     cause.Source,
+			Event:     cause.Event,
+			Timestamp: now,
+		})
 	}
 
-	return messages, nil
+	return transitions
 }
 
-// ClearMinionMessages removes all messages for a minion in a specific directory
-func (m *Manager) ClearMinionMessages(path string) error {
-	messageFile := m.getMinionMessageFile(path)
-	return os.Remove(messageFile)
+// setLastMessages caches the latest transcript messages for a status entry. They are
+// written to the store by flushLastMessages, since this runs inside status updates.
+func (m *Manager) setLastMessages(key, lastMessage, fullMessage string) {
+	m.messagesMutex.Lock()
+	defer m.messagesMutex.Unlock()
+
+	m.lastMessages[key] = lastMessage
+	m.fullLastMessages[key] = fullMessage
+	m.unsavedLastMessages[key] = true
 }
 
-// PopMinionMessage gets the oldest message and removes it from the queue
-func (m *Manager) PopMinionMessage(path string) (*MinionMessage, error) {
-	messageFile := m.getMinionMessageFile(path)
-	log.Printf("Checking for minion messages in file: %s (for path: %s)", messageFile, path)
-	messages, err := m.GetMinionMessages(path)
-	if err != nil {
-		return nil, err
+// flushLastMessages writes the messages cached by setLastMessages to the store.
+// It must not be called while the status store is locked.
+func (m *Manager) flushLastMessages() {
+	m.messagesMutex.Lock()
+	pending := make(map[string]LastMessage, len(m.unsavedLastMessages))
+	for key := range m.unsavedLastMessages {
+		pending[key] = LastMessage{Message: m.lastMessages[key], FullMessage: m.fullLastMessages[key]}
 	}
+	m.unsavedLastMessages = make(map[string]bool)
+	m.messagesMutex.Unlock()
 
-	if len(messages) == 0 {
-		return nil, nil
+	for key, lastMessage := range pending {
+		if err := m.store.SaveLastMessage(key, lastMessage.Message, lastMessage.FullMessage); err != nil {
+			log.Printf("Failed to save last message for %s: %v", key, err)
+		}
 	}
+}
 
-	// Get the first message (oldest)
-	message := messages[0]
-	
-	// Remove it from the slice
-	remainingMessages := messages[1:]
-	
-	// Save the remaining messages
-	if len(remainingMessages) == 0 {
-		// If no messages left, remove the file
-		return &message, m.ClearMinionMessages(path)
-	} else {
-		return &message, m.saveMinionMessages(path, remainingMessages)
+func (m *Manager) GetLastTranscriptMessage(transcriptPath string) (string, error) {
+	if transcriptPath == "" {
+		return "", nil
 	}
+	return m.transcriptParser.GetLastMessage(transcriptPath)
 }
 
-// saveMinionMessages saves messages to the file for a specific directory
-func (m *Manager) saveMinionMessages(path string, messages []MinionMessage) error {
-	messageFile := m.getMinionMessageFile(path)
-	log.Printf("Saving minion messages to file: %s (for path: %s)", messageFile, path)
-	
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(messageFile), 0755); err != nil {
-		return fmt.Errorf("failed to create minion messages directory: %w", err)
+func (m *Manager) GetLastTranscriptMessageFull(transcriptPath string) (string, error) {
+	if transcriptPath == "" {
+		return "", nil
 	}
+	return m.transcriptParser.GetLastMessageFull(transcriptPath)
+}
 
-	data, err := json.MarshalIndent(messages, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal minion messages: %w", err)
-	}
+func (w *FileWatcher) start() {
+	// Get initial version
+	lastVersion, _ := w.manager.store.StatusVersion()
 
-	if err := os.WriteFile(messageFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write minion messages file: %w", err)
-	}
+	ticker := time.NewTicker(1 * time.Second) // Check every second
+	defer ticker.Stop()
 
-	return nil
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			version, err := w.manager.store.StatusVersion()
+			if err != nil {
+				log.Printf("Failed to check agent status version: %v", err)
+				continue
+			}
+			if version != lastVersion {
+				lastVersion = version
+				log.Printf("Agent status changed, notifying callbacks")
+				w.manager.notifyStatusChange()
+				if w.manager.transcriptWatcher != nil {
+					w.manager.transcriptWatcher.handleAgentStatusChange()
+				}
+			}
+		}
+	}
 }
 
-// getMinionMessageFile returns the path to the message file for a specific directory
-func (m *Manager) getMinionMessageFile(path string) string {
-	// Create a safe filename from the path
-	safeName := strings.ReplaceAll(path, "/", "_")
-	safeName = strings.ReplaceAll(safeName, "\\", "_")
-	safeName = strings.ReplaceAll(safeName, ":", "_")
-	if safeName == "" {
-		safeName = "root"
-	}
-	return filepath.Join(m.configDir, "minion-messages", fmt.Sprintf("messages_%s.json", safeName))
+func (w *FileWatcher) stop() {
+	close(w.stopCh)
 }
 
+// Number of system actions shown on the dashboard
+const maxSystemActions = 50
+
 // System action tracking functions
 func (m *Manager) AddAction(actionType string, description string) {
 	m.AddActionWithCommand(actionType, description, "")
@@ -996,19 +919,11 @@ func (m *Manager) AddActionWithCommand(actionType string, description string, co
 		Command:     command,
 		Timestamp:   time.Now(),
 	}
-	
-	log.Printf("Acquiring actions mutex")
-	m.actionsMutex.Lock()
-	defer m.actionsMutex.Unlock()
-	
-	log.Printf("Adding action to slice")
-	m.systemActions = append(m.systemActions, action)
-	
-	// Keep only the last 50 actions
-	if len(m.systemActions) > 50 {
-		m.systemActions = m.systemActions[len(m.systemActions)-50:]
+
+	if err := m.store.AddSystemAction(action); err != nil {
+		log.Printf("Failed to save action: %v", err)
 	}
-	
+
 	log.Printf("Notifying status change")
 	// Notify callbacks that actions have changed (non-blocking)
 	go m.notifyStatusChange()
@@ -1016,97 +931,5 @@ func (m *Manager) AddActionWithCommand(actionType string, description string, co
 }
 
 func (m *Manager) GetSystemActions() ([]SystemAction, error) {
-	m.actionsMutex.RLock()
-	defer m.actionsMutex.RUnlock()
-	
-	// Return a copy of the slice to prevent race conditions
-	actionsCopy := make([]SystemAction, len(m.systemActions))
-	copy(actionsCopy, m.systemActions)
-	
-	return actionsCopy, nil
+	return m.store.GetSystemActions(maxSystemActions)
 }
-
-// recoverCorruptedAgentStatus attempts to recover from common JSON corruption patterns
-func (m *Manager) recoverCorruptedAgentStatus(data []byte) ([]AgentStatus, error) {
-	dataStr := string(data)
-	
-	// Common corruption: extra ']' at the end
-	if strings.HasSuffix(dataStr, "]]") {
-		log.Printf("Detected double-bracket corruption, attempting fix")
-		fixedData := []byte(strings.TrimSuffix(dataStr, "]"))
-		
-		var statuses []AgentStatus
-		if err := json.Unmarshal(fixedData, &statuses); err == nil {
-			// Successfully fixed, but remove old fields if they exist
-			return m.cleanOldFields(statuses), nil
-		}
-	}
-	
-	// Try to parse as old format and convert
-	type OldAgentStatus struct {
-		Path            string    `json:"path"`
-		Status          string    `json:"status"`
-		LastActivity    time.Time `json:"last_activity"`
-		PID             int       `json:"pid,omitempty"`
-		SessionID       string    `json:"session_id,omitempty"`
-		TranscriptPath  string    `json:"transcript_path,omitempty"`
-		LastMessage     string    `json:"last_message,omitempty"`
-		FullLastMessage string    `json:"full_last_message,omitempty"`
-	}
-	
-	var oldStatuses []OldAgentStatus
-	if err := json.Unmarshal(data, &oldStatuses); err == nil {
-		log.Printf("Successfully parsed as old format, converting to new format")
-		var newStatuses []AgentStatus
-		for _, old := range oldStatuses {
-			newStatus := AgentStatus{
-				Path:           old.Path,
-				Status:         old.Status,
-				LastActivity:   old.LastActivity,
-				PID:            old.PID,
-				SessionID:      old.SessionID,
-				TranscriptPath: old.TranscriptPath,
-			}
-			newStatuses = append(newStatuses, newStatus)
-		}
-		return newStatuses, nil
-	}
-	
-	return nil, fmt.Errorf("unable to recover corrupted JSON")
-}
-
-// cleanOldFields removes old message fields if they exist
-func (m *Manager) cleanOldFields(statuses []AgentStatus) []AgentStatus {
-	// The statuses are already in the correct format, just return them
-	return statuses
-}
-
-// ensureAgentStatusFile creates the agent status file if it doesn't exist
-func (m *Manager) ensureAgentStatusFile(statusFile string) error {
-	// Check if file already exists
-	if _, err := os.Stat(statusFile); err == nil {
-		return nil // File already exists
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("failed to check status file: %w", err)
-	}
-	
-	// Create the config directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(statusFile), 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-	
-	// Create empty agent status file
-	emptyStatuses := []AgentStatus{}
-	data, err := json.MarshalIndent(emptyStatuses, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal empty agent status: %w", err)
-	}
-	
-	if err := os.WriteFile(statusFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to create agent status file: %w", err)
-	}
-	
-	log.Printf("Created agent status file: %s", statusFile)
-	return nil
-}
-
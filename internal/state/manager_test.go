@@ -0,0 +1,111 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"coding-agent-dashboard/internal/fsutil"
+)
+
+func TestDiffStatusesRecordsEachEntrysSession(t *testing.T) {
+	oldStatuses := map[string]string{"session-a": "running", "session-b": "running", "/legacy": "running", "/repo": "running"}
+	statuses := []AgentStatus{
+		{Path: "/repo", SessionID: "session-a", Status: "waiting"},
+		{Path: "/repo", SessionID: "session-b", Status: "exited"},
+		{Path: "/legacy", Status: "idle"},
+		{Path: "/repo", Status: "error"},
+		{Path: "/repo", SessionID: "session-c", Status: "running"},
+	}
+	cause := TransitionCause{Source: "hook", Event: "Notification", SessionID: "session-a", Path: "/repo"}
+
+	transitions := diffStatuses(oldStatuses, statuses, cause)
+
+	want := map[string]string{
+		"waiting": "session-a",
+		"exited":  "session-b",
+		"idle":    "",          // A legacy entry elsewhere is not the hook's session
+		"error":   "session-a", // A legacy entry in the hook's directory falls back to its session
+		"running": "session-c",
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("got %d transitions, want %d", len(transitions), len(want))
+	}
+	for _, transition := range transitions {
+		if transition.SessionID != want[transition.NewStatus] {
+			t.Errorf("transition to %s recorded under %q, want %q", transition.NewStatus, transition.SessionID, want[transition.NewStatus])
+		}
+	}
+}
+
+func TestDiffStatusesWithoutTriggerPath(t *testing.T) {
+	statuses := []AgentStatus{{Path: "/repo", Status: "idle"}}
+	cause := TransitionCause{Source: "transcript", Event: "message", SessionID: "session-a"}
+
+	transitions := diffStatuses(map[string]string{"/repo": "running"}, statuses, cause)
+	if len(transitions) != 1 || transitions[0].SessionID != "" {
+		t.Errorf("diffStatuses() = %+v, want one transition without a session", transitions)
+	}
+}
+
+func TestTranscriptWatcherKnowsRepositoriesAddedThroughSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	if err := os.MkdirAll(filepath.Join(real, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	m, err := NewManager(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("failed to create state manager: %v", err)
+	}
+	defer m.Close()
+
+	// Stored as added, through the symlink, as older versions did
+	if err := m.SaveRepositories([]Repository{{ID: "repo_1", Path: link, Name: "link"}}); err != nil {
+		t.Fatalf("failed to save repositories: %v", err)
+	}
+
+	tw := NewTranscriptWatcher(m)
+	tw.updateKnownRepos()
+
+	for _, path := range []string{fsutil.CanonicalPath(real), fsutil.CanonicalPath(filepath.Join(real, "src")), link} {
+		if !tw.isKnownRepository(path) {
+			t.Errorf("isKnownRepository(%q) = false, want true", path)
+		}
+	}
+	if tw.isKnownRepository(fsutil.CanonicalPath(dir)) {
+		t.Errorf("isKnownRepository(%q) = true, want false", dir)
+	}
+}
+
+func TestRepositoryErrors(t *testing.T) {
+	m, err := NewManager(t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("failed to create state manager: %v", err)
+	}
+	defer m.Close()
+
+	repo, err := m.AddRepository("/repo", "repo")
+	if err != nil {
+		t.Fatalf("AddRepository() = %v", err)
+	}
+	if _, err := m.AddRepository("/repo/", "again"); !errors.Is(err, ErrExists) {
+		t.Errorf("AddRepository() of a known path = %v, want ErrExists", err)
+	}
+
+	if err := m.RemoveRepository(repo.ID); err != nil {
+		t.Fatalf("RemoveRepository() = %v", err)
+	}
+	if _, err := m.GetRepository(repo.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetRepository() after removal = %v, want ErrNotFound", err)
+	}
+	if err := m.RemoveRepository(repo.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveRepository() after removal = %v, want ErrNotFound", err)
+	}
+}
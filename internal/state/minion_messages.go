@@ -0,0 +1,399 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// How long a message waits to be delivered unless the sender says otherwise
+const DefaultMinionMessageTTL = time.Hour
+
+const (
+	// A message handed to a minion without a receipt within this long is handed out again
+	minionMessageRetryAfter = 30 * time.Second
+	// Messages still undelivered after this many attempts are marked failed
+	maxMinionMessageAttempts = 3
+	// Delivered, failed and expired messages are kept this long so senders can see the outcome
+	minionMessageRetention = 24 * time.Hour
+	// How long after a delivery the agent's status is trusted to still predate it.
+	// Claude reports the prompt as submitted well within this.
+	minionMessageSettle = 5 * time.Second
+)
+
+// AddMinionMessage queues a message for a minion, or for any minion in its path
+// when it names none, and tells the deliverer, which hands it to a connected minion
+// once the agent is ready for it, or straight away with SendNow. A ttl of 0 uses
+// the default.
+func (m *Manager) AddMinionMessage(message MinionMessage, ttl time.Duration) (*MinionMessage, error) {
+	if ttl <= 0 {
+		ttl = DefaultMinionMessageTTL
+	}
+
+	now := time.Now()
+	newMessage := message
+	newMessage.ID = fmt.Sprintf("msg_%d", now.UnixNano())
+	newMessage.Timestamp = now
+	newMessage.Status = MessageQueued
+	newMessage.ExpiresAt = now.Add(ttl)
+	if err := m.store.AddMinionMessage(newMessage); err != nil {
+		return nil, err
+	}
+
+	if m.deliverMessage != nil {
+		m.deliverMessage(newMessage.Path)
+	}
+
+	// The deliverer may already have moved it along
+	if current, err := m.GetMinionMessage(newMessage.ID); err == nil {
+		return current, nil
+	}
+	return &newMessage, nil
+}
+
+// GetMinionMessages returns messages in every state for a directory, oldest first.
+// An empty path returns the messages for every directory.
+func (m *Manager) GetMinionMessages(path string) ([]MinionMessage, error) {
+	return m.store.GetMinionMessages(path)
+}
+
+// GetMinionMessage loads a single message by ID
+func (m *Manager) GetMinionMessage(id string) (*MinionMessage, error) {
+	messages, err := m.store.GetMinionMessages("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		if message.ID == id {
+			return &message, nil
+		}
+	}
+	return nil, fmt.Errorf("minion message %w: %s", ErrNotFound, id)
+}
+
+// ClaimMinionMessage hands a minion the oldest queued message for it or for any
+// minion in its directory, counting it as a delivery attempt. Messages go out one
+// at a time and in order, so nothing is returned while the oldest is with a minion
+// that hasn't sent its receipt yet. Messages are held while the agent is busy,
+// except those marked to send now.
+func (m *Manager) ClaimMinionMessage(minion Minion) (*MinionMessage, error) {
+	agent, err := m.minionAgentStatus(minion)
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed *MinionMessage
+	err = m.store.UpdateMinionMessages(minion.Path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+		now := time.Now()
+		changed := expireMinionMessages(messages, now)
+		ready := minionAgentReady(agent, messages, now)
+
+		for i := range messages {
+			message := &messages[i]
+			if message.Status != MessageQueued || (message.MinionID != "" && message.MinionID != minion.ID) {
+				continue
+			}
+			if message.LastAttemptAt != nil && now.Sub(*message.LastAttemptAt) < minionMessageRetryAfter {
+				break
+			}
+			if message.Attempts >= maxMinionMessageAttempts {
+				if message.Error == "" {
+					message.Error = "no delivery receipt from the minion"
+				}
+				finishMinionMessage(message, MessageFailed, now)
+				changed = true
+				continue
+			}
+			if !ready && !message.SendNow {
+				// Held for the agent; a later message sent now may still go past it
+				continue
+			}
+
+			message.Attempts++
+			message.LastAttemptAt = &now
+			claim := *message
+			claimed = &claim
+			return messages, true
+		}
+		return messages, changed
+	})
+	return claimed, err
+}
+
+// CompleteMinionMessage records the outcome of a claimed message. After an error
+// the message stays queued for another attempt until its attempts run out.
+func (m *Manager) CompleteMinionMessage(path, id string, deliveryErr error) (*MinionMessage, error) {
+	var completed *MinionMessage
+	err := m.store.UpdateMinionMessages(path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+		for i := range messages {
+			message := &messages[i]
+			if message.ID != id {
+				continue
+			}
+
+			now := time.Now()
+			if deliveryErr == nil {
+				// The agent has it even if the message expired while it was being typed
+				message.Error = ""
+				finishMinionMessage(message, MessageDelivered, now)
+			} else if message.Status == MessageQueued {
+				message.Error = deliveryErr.Error()
+				if message.Attempts >= maxMinionMessageAttempts {
+					finishMinionMessage(message, MessageFailed, now)
+				}
+			}
+
+			result := *message
+			completed = &result
+			return messages, true
+		}
+		return messages, false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if completed == nil {
+		return nil, fmt.Errorf("minion message %w: %s", ErrNotFound, id)
+	}
+	return completed, nil
+}
+
+// ReleaseMinionMessage undoes the claim of a message that never reached the minion,
+// so it stays queued without using up a delivery attempt and goes out again as soon
+// as a minion can take it
+func (m *Manager) ReleaseMinionMessage(path, id string) error {
+	found := false
+	err := m.store.UpdateMinionMessages(path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+		for i := range messages {
+			message := &messages[i]
+			if message.ID != id {
+				continue
+			}
+			found = true
+			if message.Status != MessageQueued || message.Attempts == 0 {
+				return messages, false
+			}
+
+			message.Attempts--
+			message.LastAttemptAt = nil
+			return messages, true
+		}
+		return messages, false
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("minion message %w: %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// RetryMinionMessage queues a failed or expired message again with fresh attempts and TTL
+func (m *Manager) RetryMinionMessage(id string) (*MinionMessage, error) {
+	existing, err := m.GetMinionMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var retried *MinionMessage
+	var retryErr error
+	err = m.store.UpdateMinionMessages(existing.Path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+		for i := range messages {
+			message := &messages[i]
+			if message.ID != id {
+				continue
+			}
+			if message.Status != MessageFailed && message.Status != MessageExpired {
+				retryErr = fmt.Errorf("minion message %s is %s", id, message.Status)
+				return messages, false
+			}
+
+			now := time.Now()
+			message.Status = MessageQueued
+			message.ExpiresAt = now.Add(message.ExpiresAt.Sub(message.Timestamp))
+			message.Attempts = 0
+			message.LastAttemptAt = nil
+			message.FinishedAt = nil
+			message.Error = ""
+			result := *message
+			retried = &result
+			return messages, true
+		}
+		retryErr = fmt.Errorf("minion message %w: %s", ErrNotFound, id)
+		return messages, false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if retryErr != nil {
+		return nil, retryErr
+	}
+
+	if m.deliverMessage != nil {
+		m.deliverMessage(retried.Path)
+	}
+
+	if current, err := m.GetMinionMessage(id); err == nil {
+		return current, nil
+	}
+	return retried, nil
+}
+
+// SendMinionMessageNow stops holding a queued message for the agent to be ready and
+// hands it to the minion
+func (m *Manager) SendMinionMessageNow(id string) (*MinionMessage, error) {
+	existing, err := m.GetMinionMessage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sendErr error
+	err = m.store.UpdateMinionMessages(existing.Path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+		for i := range messages {
+			message := &messages[i]
+			if message.ID != id {
+				continue
+			}
+			if message.Status != MessageQueued {
+				sendErr = fmt.Errorf("minion message %s is %s", id, message.Status)
+				return messages, false
+			}
+			message.SendNow = true
+			return messages, true
+		}
+		sendErr = fmt.Errorf("minion message %w: %s", ErrNotFound, id)
+		return messages, false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return nil, sendErr
+	}
+
+	if m.deliverMessage != nil {
+		m.deliverMessage(existing.Path)
+	}
+	return m.GetMinionMessage(id)
+}
+
+// SweepMinionMessages expires queued messages past their TTL and deletes finished
+// messages past retention, reporting whether anything changed
+func (m *Manager) SweepMinionMessages() (bool, error) {
+	messages, err := m.store.GetMinionMessages("")
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	paths := make(map[string]bool)
+	for _, message := range messages {
+		if minionMessageExpired(message, now) || minionMessagePrunable(message, now) {
+			paths[message.Path] = true
+		}
+	}
+
+	for path := range paths {
+		err := m.store.UpdateMinionMessages(path, func(messages []MinionMessage) ([]MinionMessage, bool) {
+			changed := expireMinionMessages(messages, now)
+
+			kept := messages[:0]
+			for _, message := range messages {
+				if minionMessagePrunable(message, now) {
+					changed = true
+					continue
+				}
+				kept = append(kept, message)
+			}
+			return kept, changed
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(paths) > 0, nil
+}
+
+// minionAgentStatus returns the most recently active live session of a minion's
+// agent, or nil when no hook has reported one. Sessions are matched by the minion's
+// PID, falling back to sessions in its directory that no other minion wraps. A
+// minion without a PID stands for any agent in its directory.
+func (m *Manager) minionAgentStatus(minion Minion) (*AgentStatus, error) {
+	statuses, err := m.GetAgentStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent status: %w", err)
+	}
+
+	path := filepath.Clean(minion.Path)
+	var agent *AgentStatus
+	for i := range statuses {
+		status := &statuses[i]
+		if status.Finished() {
+			continue
+		}
+		wrapped := minion.PID != 0 && status.MinionPID == minion.PID
+		if !wrapped {
+			if minion.PID != 0 && status.MinionPID != 0 {
+				continue // Another minion's agent
+			}
+			statusPath := filepath.Clean(status.Path)
+			if statusPath != path && !strings.HasPrefix(statusPath, path+string(filepath.Separator)) {
+				continue
+			}
+		}
+		if agent == nil || status.LastActivity.After(agent.LastActivity) {
+			agent = status
+		}
+	}
+	return agent, nil
+}
+
+// minionAgentReady reports whether the agent can take a message without it landing
+// in the middle of a response: it is waiting or idle, and has reported in since the
+// last message was delivered. Agents without hooks report nothing to wait on.
+func minionAgentReady(agent *AgentStatus, messages []MinionMessage, now time.Time) bool {
+	if agent == nil {
+		return true
+	}
+	if agent.Status != "waiting" && agent.Status != "idle" {
+		return false
+	}
+
+	for _, message := range messages {
+		if message.Status != MessageDelivered || message.FinishedAt == nil {
+			continue
+		}
+		if agent.LastActivity.Before(*message.FinishedAt) && now.Sub(*message.FinishedAt) < minionMessageSettle {
+			return false
+		}
+	}
+	return true
+}
+
+// expireMinionMessages marks queued messages past their TTL as expired
+func expireMinionMessages(messages []MinionMessage, now time.Time) bool {
+	changed := false
+	for i := range messages {
+		if minionMessageExpired(messages[i], now) {
+			finishMinionMessage(&messages[i], MessageExpired, now)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func minionMessageExpired(message MinionMessage, now time.Time) bool {
+	return message.Status == MessageQueued && now.After(message.ExpiresAt)
+}
+
+func minionMessagePrunable(message MinionMessage, now time.Time) bool {
+	return message.FinishedAt != nil && now.Sub(*message.FinishedAt) > minionMessageRetention
+}
+
+func finishMinionMessage(message *MinionMessage, status string, now time.Time) {
+	message.Status = status
+	message.FinishedAt = &now
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"testing"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+func TestReleaseMinionMessageKeepsTheAttempt(t *testing.T) {
+	for _, backend := range []string{config.StorageJSON, config.StorageSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			m, err := NewManager(newTestConfigDir(t, backend), true)
+			if err != nil {
+				t.Fatalf("failed to create state manager: %v", err)
+			}
+			defer m.Close()
+
+			minion := Minion{ID: "minion_1", Path: "/repo"}
+			queued, err := m.AddMinionMessage(MinionMessage{Path: "/repo", Message: "hello", SendNow: true}, 0)
+			if err != nil {
+				t.Fatalf("failed to queue message: %v", err)
+			}
+
+			// More failed sends than there are attempts
+			for i := 0; i < maxMinionMessageAttempts+1; i++ {
+				claimed, err := m.ClaimMinionMessage(minion)
+				if err != nil {
+					t.Fatalf("failed to claim message: %v", err)
+				}
+				if claimed == nil || claimed.ID != queued.ID {
+					t.Fatalf("claim %d got %+v, want message %s", i, claimed, queued.ID)
+				}
+				if err := m.ReleaseMinionMessage("/repo", claimed.ID); err != nil {
+					t.Fatalf("failed to release message: %v", err)
+				}
+			}
+
+			message, err := m.GetMinionMessage(queued.ID)
+			if err != nil {
+				t.Fatalf("failed to get message: %v", err)
+			}
+			if message.Status != MessageQueued || message.Attempts != 0 || message.LastAttemptAt != nil {
+				t.Errorf("released message = %+v, want it queued without attempts", message)
+			}
+
+			if err := m.ReleaseMinionMessage("/repo", "msg_missing"); err == nil {
+				t.Error("releasing a missing message succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/process"
+)
+
+// Minion is a running minion process. Each minion registers itself so messages
+// can be sent to it rather than to whichever minion works in the same directory.
+type Minion struct {
+	ID           string    `json:"id"`
+	PID          int       `json:"pid"`
+	PIDStartTime uint64    `json:"pid_start_time,omitempty"` // Start time of PID, to detect reuse
+	Path         string    `json:"path"`                     // Working directory
+	Command      []string  `json:"command"`                  // Agent command line
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// MinionIDEnv gives a minion its ID: the server sets it for minions it launches,
+// and it carries the ID across a restart, which re-executes the minion
+const MinionIDEnv = "CODING_AGENT_DASHBOARD_MINION_ID"
+
+// NewMinionID generates an ID for a minion process
+func NewMinionID() string {
+	return fmt.Sprintf("minion_%d", time.Now().UnixNano())
+}
+
+// RegisterMinion records a running minion
+func (m *Manager) RegisterMinion(minion Minion) error {
+	return m.store.SaveMinion(minion)
+}
+
+// UnregisterMinion removes a minion that is exiting
+func (m *Manager) UnregisterMinion(id string) error {
+	return m.store.DeleteMinion(id)
+}
+
+// GetMinion loads a registered minion by ID. A minion whose process has gone away
+// without unregistering is removed and reported as not found.
+func (m *Manager) GetMinion(id string) (*Minion, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("minion %w: %s", ErrNotFound, id)
+	}
+
+	minion, err := m.store.GetMinion(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.minionAlive(*minion) {
+		return nil, fmt.Errorf("minion %w: %s", ErrNotFound, id)
+	}
+
+	return minion, nil
+}
+
+// GetMinions returns the registered minions, oldest first. A path limits them to
+// minions working in that directory.
+func (m *Manager) GetMinions(path string) ([]Minion, error) {
+	registered, err := m.store.GetMinions()
+	if err != nil {
+		return nil, err
+	}
+
+	minions := []Minion{}
+	for _, minion := range registered {
+		if !m.minionAlive(minion) {
+			continue
+		}
+		if path != "" && filepath.Clean(minion.Path) != filepath.Clean(path) {
+			continue
+		}
+		minions = append(minions, minion)
+	}
+
+	sort.Slice(minions, func(i, j int) bool {
+		return minions[i].StartedAt.Before(minions[j].StartedAt)
+	})
+
+	return minions, nil
+}
+
+// minionAlive reports whether a minion's process is still running, unregistering
+// it if not
+func (m *Manager) minionAlive(minion Minion) bool {
+	if process.Alive(minion.PID, minion.PIDStartTime) {
+		return true
+	}
+
+	log.Printf("Minion %s (PID %d) is gone, removing it", minion.ID, minion.PID)
+	if err := m.UnregisterMinion(minion.ID); err != nil {
+		log.Printf("Failed to unregister minion %s: %v", minion.ID, err)
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package state
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Repository struct {
 	ID        string    `json:"id"`
@@ -11,18 +14,72 @@ type Repository struct {
 }
 
 type Worktree struct {
-	Path   string `json:"path"`
-	Branch string `json:"branch"`
-	IsMain bool   `json:"is_main"`
+	Path           string          `json:"path"`
+	Branch         string          `json:"branch"`
+	IsMain         bool            `json:"is_main"`
+	Head           string          `json:"head,omitempty"`     // Commit checked out
+	Bare           bool            `json:"bare,omitempty"`     // The repository itself, with no files checked out
+	Detached       bool            `json:"detached,omitempty"` // HEAD is not on a branch
+	Locked         bool            `json:"locked,omitempty"`   // Protected from pruning and removal
+	LockReason     string          `json:"lock_reason,omitempty"`
+	Prunable       bool            `json:"prunable,omitempty"` // Directory is gone; git can prune it
+	PrunableReason string          `json:"prunable_reason,omitempty"`
+	GitStatus      *WorktreeStatus `json:"git_status,omitempty"` // Changes and commits, when asked for
 }
 
+// WorktreeStatus is what has changed in a worktree and how its branch compares
+// to its upstream and to the repository's default branch
+type WorktreeStatus struct {
+	Staged      int     `json:"staged"`   // Files with changes in the index
+	Unstaged    int     `json:"unstaged"` // Tracked files with changes not yet staged
+	Untracked   int     `json:"untracked"`
+	Conflicted  int     `json:"conflicted"` // Files with unresolved merge conflicts
+	Upstream    string  `json:"upstream,omitempty"`
+	Ahead       int     `json:"ahead"`                 // Commits not on the upstream
+	Behind      int     `json:"behind"`                // Upstream commits not on this branch
+	MainBranch  string  `json:"main_branch,omitempty"` // Default branch the counts below compare with, e.g. origin/main
+	AheadOfMain int     `json:"ahead_of_main"`
+	BehindMain  int     `json:"behind_main"`
+	LastCommit  *Commit `json:"last_commit,omitempty"`
+}
+
+// Commit is a summary of a git commit
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Time    time.Time `json:"time"`
+}
+
+// AgentStatus is one Claude Code session; a worktree can have several at once
 type AgentStatus struct {
-	Path           string    `json:"path"`
-	Status         string    `json:"status"` // running, idle, paused, error
-	LastActivity   time.Time `json:"last_activity"`
-	PID            int       `json:"pid,omitempty"`
-	SessionID      string    `json:"session_id,omitempty"`
-	TranscriptPath string    `json:"transcript_path,omitempty"`
+	Path           string     `json:"path"`
+	Status         string     `json:"status"`                // running, waiting, idle, compacting, ended, exited
+	Activity       string     `json:"activity,omitempty"`    // Human readable description of the last lifecycle event
+	LastPrompt     string     `json:"last_prompt,omitempty"` // Most recent prompt submitted by the user
+	LastActivity   time.Time  `json:"last_activity"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+	ExitedAt       *time.Time `json:"exited_at,omitempty"`      // When the Claude Code process was found gone
+	ExitCode       *int       `json:"exit_code,omitempty"`      // Exit code reported by the minion wrapping the session
+	PID            int        `json:"pid,omitempty"`            // Claude Code process running the session
+	PIDStartTime   uint64     `json:"pid_start_time,omitempty"` // Start time of PID, to detect reuse
+	MinionPID      int        `json:"minion_pid,omitempty"`     // Minion process wrapping the session, if any
+	SessionID      string     `json:"session_id,omitempty"`
+	TranscriptPath string     `json:"transcript_path,omitempty"`
+}
+
+// Key identifies the status entry: its session ID, or its path for entries
+// recorded before sessions were tracked
+func (s AgentStatus) Key() string {
+	if s.SessionID != "" {
+		return s.SessionID
+	}
+	return s.Path
+}
+
+// Finished reports whether the session is over, either ended cleanly or exited
+func (s AgentStatus) Finished() bool {
+	return s.Status == "ended" || s.Status == "exited"
 }
 
 // AgentStatusWithMessages is used for API responses that include last messages from memory
@@ -38,11 +95,38 @@ type RepositoryWithWorktrees struct {
 	Status    []AgentStatusWithMessages `json:"status"`
 }
 
+// Minion message lifecycle
+const (
+	MessageQueued    = "queued"    // Waiting to be written to the agent
+	MessageDelivered = "delivered" // Written to the agent's terminal
+	MessageFailed    = "failed"    // Every delivery attempt failed
+	MessageExpired   = "expired"   // Its TTL passed before it could be delivered
+)
+
 type MinionMessage struct {
-	ID        string    `json:"id"`
-	Path      string    `json:"path"`      // Working directory of the minion
-	Message   string    `json:"message"`   // Message to send to stdin
-	Timestamp time.Time `json:"timestamp"`
+	ID            string     `json:"id"`
+	Path          string     `json:"path"`                // Working directory of the minion
+	MinionID      string     `json:"minion_id,omitempty"` // Minion it is for; any minion in Path when empty
+	Message       string     `json:"message"`             // Message to send to stdin
+	Timestamp     time.Time  `json:"timestamp"`
+	Status        string     `json:"status"` // queued, delivered, failed, expired
+	ExpiresAt     time.Time  `json:"expires_at"`
+	Attempts      int        `json:"attempts,omitempty"`        // Times the message was handed to a minion
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"` // When it was last handed to a minion
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`     // When it was delivered, failed or expired
+	Error         string     `json:"error,omitempty"`           // Why the last attempt failed
+	SendNow       bool       `json:"send_now,omitempty"`        // Deliver without waiting for the agent to be ready
+}
+
+// withDefaults fills in the lifecycle of messages queued before it was tracked
+func (m MinionMessage) withDefaults() MinionMessage {
+	if m.Status == "" {
+		m.Status = MessageQueued
+	}
+	if m.ExpiresAt.IsZero() {
+		m.ExpiresAt = m.Timestamp.Add(DefaultMinionMessageTTL)
+	}
+	return m
 }
 
 type SystemAction struct {
@@ -51,4 +135,100 @@ type SystemAction struct {
 	Description string    `json:"description"` // Human readable description
 	Command     string    `json:"command,omitempty"` // Optional actual command text
 	Timestamp   time.Time `json:"timestamp"`
-}
\ No newline at end of file
+}
+
+// ToolApproval is a PreToolUse call blocked in hook mode until the dashboard decides it
+type ToolApproval struct {
+	ID        string          `json:"id"`
+	Path      string          `json:"path"` // Working directory of the agent
+	SessionID string          `json:"session_id,omitempty"`
+	ToolName  string          `json:"tool_name"`
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	Status    string          `json:"status"`             // pending, decided, expired, abandoned
+	Decision  string          `json:"decision,omitempty"` // allow, deny, ask
+	Reason    string          `json:"reason,omitempty"`
+	HookPID   int             `json:"hook_pid"` // Hook process blocked on this approval
+	CreatedAt time.Time       `json:"created_at"`
+	DecidedAt *time.Time      `json:"decided_at,omitempty"`
+}
+
+// PolicyDecision records a permission decision returned to Claude Code by a PreToolUse hook
+type PolicyDecision struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	SessionID string    `json:"session_id,omitempty"`
+	ToolName  string    `json:"tool_name"`
+	Target    string    `json:"target,omitempty"` // Command or file path the tool acted on
+	Decision  string    `json:"decision"`         // allow, deny, ask
+	Reason    string    `json:"reason,omitempty"`
+	Rule      string    `json:"rule,omitempty"` // Name of the matching policy rule
+	Source    string    `json:"source"`         // repository, global or dashboard
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Causes of agent status transitions
+const (
+	CauseHook       = "hook"       // A Claude Code hook event
+	CauseTranscript = "transcript" // Inferred from transcript activity
+	CauseProcess    = "process"    // The agent's process exited
+)
+
+// TransitionCause describes what triggered an agent status update
+type TransitionCause struct {
+	Source    string // hook, transcript or process
+	Event     string // Hook event name or kind of transcript inference
+	SessionID string // Session reported by the trigger, if known
+	Path      string // Working directory reported by the trigger, if known
+}
+
+// StatusTransition records an agent moving from one status to another
+type StatusTransition struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	SessionID string    `json:"session_id,omitempty"`
+	OldStatus string    `json:"old_status"` // Empty when the agent is first seen
+	NewStatus string    `json:"new_status"`
+	Cause     string    `json:"cause"`           // hook, transcript or process
+	Event     string    `json:"event,omitempty"` // Hook event name or kind of transcript inference
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Recording is an asciicast v2 recording of a minion's terminal
+type Recording struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"` // Working directory of the minion
+	Command   string    `json:"command,omitempty"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"` // Last write; keeps moving while recording
+	Size      int64     `json:"size"`
+}
+
+// How a message schedule decides when to send
+const (
+	ScheduleAt   = "at"   // Once, at a given time
+	ScheduleIdle = "idle" // Each time the agent has been idle for a number of minutes
+	ScheduleCron = "cron" // On a cron expression
+)
+
+// MessageSchedule sends a minion message at a time, after the agent has been idle
+// for a while, or on a cron expression
+type MessageSchedule struct {
+	ID            string     `json:"id"`
+	Path          string     `json:"path"`                // Working directory of the minion
+	MinionID      string     `json:"minion_id,omitempty"` // Minion the messages are for; any minion in Path when empty
+	Message       string     `json:"message"`
+	Kind          string     `json:"kind"` // at, idle or cron
+	At            *time.Time `json:"at,omitempty"`
+	IdleMinutes   int        `json:"idle_minutes,omitempty"`
+	Cron          string     `json:"cron,omitempty"`        // Standard five-field expression or a descriptor like @daily, in local time
+	TTLSeconds    int        `json:"ttl_seconds,omitempty"` // TTL of each message sent; 0 uses the default
+	Enabled       bool       `json:"enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	NextRun       *time.Time `json:"next_run,omitempty"` // Nil while an idle schedule's agent is busy, and once a one-off has run
+	LastRun       *time.Time `json:"last_run,omitempty"`
+	LastMessageID string     `json:"last_message_id,omitempty"`
+	LastOutcome   string     `json:"last_outcome,omitempty"` // Status of the last message sent, or failed if it couldn't be queued
+	LastError     string     `json:"last_error,omitempty"`
+}
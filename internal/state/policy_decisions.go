@@ -0,0 +1,87 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Maximum number of decisions returned by GetPolicyDecisions
+const maxPolicyDecisions = 500
+
+// RecordPolicyDecision appends a decision to the decision log. The log is
+// append-only JSON lines so concurrent hook processes can write without clobbering.
+func (m *Manager) RecordPolicyDecision(decision PolicyDecision) error {
+	if decision.ID == "" {
+		decision.ID = fmt.Sprintf("decision_%d", time.Now().UnixNano())
+	}
+	if decision.Timestamp.IsZero() {
+		decision.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy decision: %w", err)
+	}
+
+	f, err := os.OpenFile(m.getPolicyDecisionsFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open policy decisions file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write policy decision: %w", err)
+	}
+
+	return nil
+}
+
+// GetPolicyDecisions returns the most recent decisions, newest first, optionally
+// filtered to a working directory
+func (m *Manager) GetPolicyDecisions(path string) ([]PolicyDecision, error) {
+	f, err := os.Open(m.getPolicyDecisionsFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []PolicyDecision{}, nil
+		}
+		return nil, fmt.Errorf("failed to open policy decisions file: %w", err)
+	}
+	defer f.Close()
+
+	var decisions []PolicyDecision
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var decision PolicyDecision
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err != nil {
+			continue // Skip malformed lines
+		}
+		if path != "" && decision.Path != path {
+			continue
+		}
+		decisions = append(decisions, decision)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read policy decisions file: %w", err)
+	}
+
+	if len(decisions) > maxPolicyDecisions {
+		decisions = decisions[len(decisions)-maxPolicyDecisions:]
+	}
+
+	// Reverse to show most recent first
+	for i, j := 0, len(decisions)-1; i < j; i, j = i+1, j-1 {
+		decisions[i], decisions[j] = decisions[j], decisions[i]
+	}
+
+	return decisions, nil
+}
+
+// getPolicyDecisionsFile returns the path to the decision log
+func (m *Manager) getPolicyDecisionsFile() string {
+	return filepath.Join(m.configDir, "policy-decisions.jsonl")
+}
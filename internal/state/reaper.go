@@ -0,0 +1,105 @@
+package state
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"coding-agent-dashboard/internal/process"
+)
+
+// How often the server checks that agents' Claude Code processes are still alive
+const reapInterval = 5 * time.Second
+
+// ReapExitedAgents marks live sessions whose Claude Code process is gone as exited.
+// Sessions recorded without a process start time are left alone, since their PID
+// can't be told apart from a reused one.
+func (m *Manager) ReapExitedAgents() error {
+	statuses, err := m.GetAgentStatus()
+	if err != nil {
+		return err
+	}
+
+	// Most checks find nothing, so only take the status lock when something died
+	anyExited := false
+	for _, status := range statuses {
+		if processExited(status) {
+			anyExited = true
+			break
+		}
+	}
+	if !anyExited {
+		return nil
+	}
+
+	cause := TransitionCause{Source: CauseProcess, Event: "process_gone"}
+	return m.UpdateAgentStatus(cause, func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		changed := false
+		for i, status := range statuses {
+			if processExited(status) {
+				log.Printf("Claude Code process %d for session %s is gone, marking exited", status.PID, status.Key())
+				markExited(&statuses[i], "Process exited")
+				changed = true
+			}
+		}
+		return statuses, changed
+	})
+}
+
+// MarkMinionSessionsExited marks every live session wrapped by a minion process as
+// exited with the agent's exit code
+func (m *Manager) MarkMinionSessionsExited(minionPID, exitCode int) error {
+	cause := TransitionCause{Source: CauseProcess, Event: "minion_exit"}
+	return m.UpdateAgentStatus(cause, func(statuses []AgentStatus) ([]AgentStatus, bool) {
+		changed := false
+		for i, status := range statuses {
+			if status.MinionPID == minionPID && !status.Finished() {
+				markExited(&statuses[i], fmt.Sprintf("Process exited with code %d", exitCode))
+				statuses[i].ExitCode = &exitCode
+				changed = true
+			}
+		}
+		return statuses, changed
+	})
+}
+
+// processExited reports whether a live session's Claude Code process has gone away
+func processExited(status AgentStatus) bool {
+	if status.Finished() || status.PID == 0 || status.PIDStartTime == 0 {
+		return false
+	}
+	return !process.Alive(status.PID, status.PIDStartTime)
+}
+
+func markExited(status *AgentStatus, activity string) {
+	now := time.Now()
+	status.Status = "exited"
+	status.Activity = activity
+	status.ExitedAt = &now
+}
+
+// reapLoop checks agent processes and the hooks waiting on approvals periodically
+// until stopped
+func (m *Manager) reapLoop(stopCh chan bool) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := m.ReapExitedAgents(); err != nil {
+				log.Printf("Failed to reap exited agents: %v", err)
+			}
+
+			changed, err := m.ReapToolApprovals()
+			if err != nil {
+				log.Printf("Failed to reap approvals: %v", err)
+			}
+			if changed {
+				m.notifyStatusChange()
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/terminal"
+)
+
+// StartRecording creates an asciicast recording for a minion's terminal. The
+// working directory is stored as the recording's title so it can be listed by path.
+func (m *Manager) StartRecording(path, command string, width, height int) (*terminal.Recorder, *Recording, error) {
+	dir := m.getRecordingsDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	id := fmt.Sprintf("recording_%d", time.Now().UnixNano())
+	recorder, err := terminal.NewRecorder(filepath.Join(dir, id+".cast"), terminal.Header{
+		Width:   width,
+		Height:  height,
+		Command: command,
+		Title:   path,
+		Env:     map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recording := &Recording{
+		ID:        id,
+		Path:      path,
+		Command:   command,
+		Width:     width,
+		Height:    height,
+		StartedAt: time.Now(),
+	}
+	return recorder, recording, nil
+}
+
+// GetRecordings lists recordings, newest first, optionally only those for one path
+func (m *Manager) GetRecordings(path string) ([]Recording, error) {
+	files, err := filepath.Glob(filepath.Join(m.getRecordingsDir(), "*.cast"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	recordings := []Recording{}
+	for _, file := range files {
+		recording, err := readRecording(file)
+		if err != nil {
+			// Skip recordings that were just created or are otherwise unreadable
+			continue
+		}
+		if path != "" && recording.Path != path {
+			continue
+		}
+		recordings = append(recordings, *recording)
+	}
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartedAt.After(recordings[j].StartedAt)
+	})
+	return recordings, nil
+}
+
+// OpenRecording opens a recording's asciicast file for playback
+func (m *Manager) OpenRecording(id string) (*os.File, *Recording, error) {
+	// IDs become file names, so refuse anything that could escape the directory
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, nil, fmt.Errorf("recording %w: %s", ErrNotFound, id)
+	}
+
+	file := filepath.Join(m.getRecordingsDir(), id+".cast")
+	recording, err := readRecording(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("recording %w: %s", ErrNotFound, id)
+		}
+		return nil, nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	return f, recording, nil
+}
+
+// readRecording describes a recording from its file and asciicast header
+func readRecording(file string) (*Recording, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := terminal.ReadHeader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recording{
+		ID:        strings.TrimSuffix(filepath.Base(file), ".cast"),
+		Path:      header.Title,
+		Command:   header.Command,
+		Width:     header.Width,
+		Height:    header.Height,
+		StartedAt: time.Unix(header.Timestamp, 0),
+		UpdatedAt: info.ModTime(),
+		Size:      info.Size(),
+	}, nil
+}
+
+// getRecordingsDir returns the directory holding minion terminal recordings
+func (m *Manager) getRecordingsDir() string {
+	return filepath.Join(m.configDir, "recordings")
+}
@@ -0,0 +1,314 @@
+package state
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// How often the server looks for schedules that are due
+const scheduleInterval = 15 * time.Second
+
+// AddSchedule validates and saves a new message schedule
+func (m *Manager) AddSchedule(schedule MessageSchedule) (*MessageSchedule, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	if schedule.Kind == ScheduleAt && !schedule.At.After(time.Now()) {
+		return nil, fmt.Errorf("schedule time %s is in the past", schedule.At.Format(time.RFC3339))
+	}
+
+	now := time.Now()
+	schedule.ID = fmt.Sprintf("schedule_%d", now.UnixNano())
+	schedule.Enabled = true
+	schedule.CreatedAt = now
+	schedule.NextRun = m.nextScheduleRun(schedule, now)
+
+	if err := m.store.SaveSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetSchedule loads a single schedule by ID
+func (m *Manager) GetSchedule(id string) (*MessageSchedule, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("schedule %w: %s", ErrNotFound, id)
+	}
+	return m.store.GetSchedule(id)
+}
+
+// GetSchedules returns the schedules for a directory, or all of them for an empty
+// path, oldest first
+func (m *Manager) GetSchedules(path string) ([]MessageSchedule, error) {
+	all, err := m.store.GetSchedules()
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []MessageSchedule{}
+	for _, schedule := range all {
+		if path != "" && filepath.Clean(schedule.Path) != filepath.Clean(path) {
+			continue
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+
+	return schedules, nil
+}
+
+// SetScheduleEnabled pauses or resumes a schedule
+func (m *Manager) SetScheduleEnabled(id string, enabled bool) (*MessageSchedule, error) {
+	schedule, err := m.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Worked out before the update, which must not read the store itself
+	var nextRun *time.Time
+	if enabled {
+		schedule.Enabled = true
+		nextRun = m.nextScheduleRun(*schedule, time.Now())
+	}
+
+	var updated *MessageSchedule
+	err = m.store.UpdateSchedule(id, func(schedule *MessageSchedule) bool {
+		schedule.Enabled = enabled
+		schedule.NextRun = nextRun
+		result := *schedule
+		updated = &result
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteSchedule removes a schedule
+func (m *Manager) DeleteSchedule(id string) error {
+	if _, err := m.GetSchedule(id); err != nil {
+		return err
+	}
+	return m.store.DeleteSchedule(id)
+}
+
+// RunDueSchedules queues the message of every enabled schedule that is due, and
+// refreshes next runs and the outcome of earlier messages. It reports whether
+// any schedule changed.
+func (m *Manager) RunDueSchedules() (bool, error) {
+	schedules, err := m.GetSchedules("")
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for _, schedule := range schedules {
+		// Running a schedule queues messages and looks up minions, so it happens
+		// outside the store update and the result is merged in afterwards
+		before := schedule
+		if !m.runSchedule(&schedule, time.Now()) {
+			continue
+		}
+
+		err := m.store.UpdateSchedule(schedule.ID, func(stored *MessageSchedule) bool {
+			mergeScheduleRun(stored, before, schedule)
+			return true
+		})
+		if err != nil {
+			log.Printf("Failed to save run of schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// mergeScheduleRun records a run on the stored schedule. The run's outcome is always
+// kept, but a pause or resume made while it ran wins over the run's next run.
+func mergeScheduleRun(stored *MessageSchedule, before, after MessageSchedule) {
+	stored.LastRun = after.LastRun
+	stored.LastMessageID = after.LastMessageID
+	stored.LastOutcome = after.LastOutcome
+	stored.LastError = after.LastError
+
+	if stored.Enabled == before.Enabled && sameTime(stored.NextRun, before.NextRun) {
+		stored.Enabled = after.Enabled
+		stored.NextRun = after.NextRun
+	}
+}
+
+// runSchedule sends a schedule's message if it is due and brings its next run and
+// last outcome up to date, reporting whether anything changed
+func (m *Manager) runSchedule(schedule *MessageSchedule, now time.Time) bool {
+	before := *schedule
+
+	if schedule.LastMessageID != "" && schedule.LastOutcome == MessageQueued {
+		if message, err := m.GetMinionMessage(schedule.LastMessageID); err == nil {
+			schedule.LastOutcome = message.Status
+			schedule.LastError = message.Error
+		}
+	}
+
+	if !schedule.Enabled {
+		return !sameScheduleState(before, *schedule)
+	}
+
+	// Idle schedules follow the agent, so their next run is worked out afresh each time
+	if schedule.Kind == ScheduleIdle {
+		schedule.NextRun = m.nextScheduleRun(*schedule, now)
+	}
+
+	if schedule.NextRun != nil && !schedule.NextRun.After(now) {
+		message, err := m.AddMinionMessage(MinionMessage{
+			Path:     schedule.Path,
+			MinionID: schedule.MinionID,
+			Message:  schedule.Message,
+		}, time.Duration(schedule.TTLSeconds)*time.Second)
+
+		schedule.LastRun = &now
+		if err != nil {
+			log.Printf("Failed to queue message for schedule %s: %v", schedule.ID, err)
+			schedule.LastMessageID = ""
+			schedule.LastOutcome = MessageFailed
+			schedule.LastError = err.Error()
+		} else {
+			log.Printf("Schedule %s queued message %s for %s", schedule.ID, message.ID, schedule.Path)
+			schedule.LastMessageID = message.ID
+			schedule.LastOutcome = message.Status
+			schedule.LastError = message.Error
+			m.AddAction("schedule", fmt.Sprintf("⏰ Scheduled message for %s: %s", schedule.Path, schedule.Message))
+		}
+
+		// Runs missed while the server was down are caught up once, not once per miss
+		schedule.NextRun = m.nextScheduleRun(*schedule, now)
+		if schedule.Kind == ScheduleAt {
+			schedule.Enabled = false
+		}
+	}
+
+	return !sameScheduleState(before, *schedule)
+}
+
+// nextScheduleRun works out when a schedule should next send after a given time,
+// or nil if it has nothing coming up
+func (m *Manager) nextScheduleRun(schedule MessageSchedule, after time.Time) *time.Time {
+	switch schedule.Kind {
+	case ScheduleAt:
+		if schedule.LastRun != nil {
+			return nil
+		}
+		next := *schedule.At
+		return &next
+
+	case ScheduleCron:
+		parsed, err := cron.ParseStandard(schedule.Cron)
+		if err != nil {
+			return nil
+		}
+		next := parsed.Next(after)
+		return &next
+
+	case ScheduleIdle:
+		minion := Minion{ID: schedule.MinionID, Path: schedule.Path}
+		if schedule.MinionID != "" {
+			registered, err := m.GetMinion(schedule.MinionID)
+			if err != nil {
+				return nil
+			}
+			minion = *registered
+		}
+
+		agent, err := m.minionAgentStatus(minion)
+		if err != nil || agent == nil || (agent.Status != "idle" && agent.Status != "waiting") {
+			return nil
+		}
+		// Once per idle spell: the agent has to do something before it can be nudged again
+		if schedule.LastRun != nil && !schedule.LastRun.Before(agent.LastActivity) {
+			return nil
+		}
+		next := agent.LastActivity.Add(time.Duration(schedule.IdleMinutes) * time.Minute)
+		return &next
+	}
+	return nil
+}
+
+// validateSchedule checks that a schedule has a target, a message and exactly the
+// settings its kind needs
+func validateSchedule(schedule MessageSchedule) error {
+	if schedule.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if schedule.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	if schedule.TTLSeconds < 0 {
+		return fmt.Errorf("ttl_seconds must not be negative")
+	}
+
+	switch schedule.Kind {
+	case ScheduleAt:
+		if schedule.At == nil {
+			return fmt.Errorf("at is required for an at schedule")
+		}
+	case ScheduleIdle:
+		if schedule.IdleMinutes <= 0 {
+			return fmt.Errorf("idle_minutes must be positive for an idle schedule")
+		}
+	case ScheduleCron:
+		if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", schedule.Cron, err)
+		}
+	default:
+		return fmt.Errorf("unsupported schedule kind %q (use %s, %s or %s)", schedule.Kind, ScheduleAt, ScheduleIdle, ScheduleCron)
+	}
+	return nil
+}
+
+// sameScheduleState reports whether two versions of a schedule would look the same
+// on the dashboard
+func sameScheduleState(a, b MessageSchedule) bool {
+	return a.Enabled == b.Enabled &&
+		sameTime(a.NextRun, b.NextRun) &&
+		sameTime(a.LastRun, b.LastRun) &&
+		a.LastMessageID == b.LastMessageID &&
+		a.LastOutcome == b.LastOutcome &&
+		a.LastError == b.LastError
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// runSchedulesLoop sends scheduled messages as they come due until stopped
+func (m *Manager) runSchedulesLoop(stopCh chan bool) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			changed, err := m.RunDueSchedules()
+			if err != nil {
+				log.Printf("Failed to run schedules: %v", err)
+			}
+			if changed {
+				m.notifyStatusChange()
+			}
+		}
+	}
+}
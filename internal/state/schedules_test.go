@@ -0,0 +1,41 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeScheduleRun(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	next := now.Add(24 * time.Hour)
+
+	before := MessageSchedule{ID: "schedule_1", Kind: ScheduleCron, Enabled: true, NextRun: &now}
+	after := before
+	after.LastRun = &now
+	after.LastMessageID = "message_1"
+	after.LastOutcome = MessageQueued
+	after.NextRun = &next
+
+	t.Run("unchanged schedule takes the run", func(t *testing.T) {
+		stored := before
+		mergeScheduleRun(&stored, before, after)
+
+		if !sameScheduleState(stored, after) {
+			t.Errorf("merged schedule = %+v, want %+v", stored, after)
+		}
+	})
+
+	t.Run("pause during the run wins", func(t *testing.T) {
+		stored := before
+		stored.Enabled = false
+		stored.NextRun = nil
+		mergeScheduleRun(&stored, before, after)
+
+		if stored.Enabled || stored.NextRun != nil {
+			t.Errorf("merged schedule enabled=%v next_run=%v, want it to stay paused", stored.Enabled, stored.NextRun)
+		}
+		if stored.LastMessageID != "message_1" || stored.LastRun == nil {
+			t.Errorf("merged schedule lost the run: %+v", stored)
+		}
+	})
+}
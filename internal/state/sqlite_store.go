@@ -0,0 +1,739 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order; a migration's version is its index + 1.
+// Never edit a released migration, append a new one instead.
+var sqliteMigrations = []string{
+	// 1: initial schema
+	`CREATE TABLE repositories (
+		id         TEXT PRIMARY KEY,
+		path       TEXT NOT NULL UNIQUE,
+		name       TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+	CREATE TABLE agent_status (
+		path          TEXT PRIMARY KEY,
+		status        TEXT NOT NULL,
+		session_id    TEXT NOT NULL DEFAULT '',
+		last_activity TEXT NOT NULL,
+		data          TEXT NOT NULL -- Full AgentStatus as JSON
+	);
+	CREATE TABLE minion_messages (
+		seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+		id        TEXT NOT NULL UNIQUE,
+		path      TEXT NOT NULL,
+		message   TEXT NOT NULL,
+		timestamp TEXT NOT NULL
+	);
+	CREATE INDEX idx_minion_messages_path ON minion_messages (path, seq);
+	CREATE TABLE system_actions (
+		seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+		id          TEXT NOT NULL,
+		type        TEXT NOT NULL,
+		description TEXT NOT NULL,
+		command     TEXT NOT NULL DEFAULT '',
+		timestamp   TEXT NOT NULL
+	);
+	CREATE TABLE last_messages (
+		path         TEXT PRIMARY KEY,
+		message      TEXT NOT NULL,
+		full_message TEXT NOT NULL,
+		updated_at   TEXT NOT NULL
+	);
+	CREATE TABLE meta (
+		key   TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);
+	INSERT INTO meta (key, value) VALUES ('status_version', 0);`,
+
+	// 2: status transition history
+	`CREATE TABLE status_transitions (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		id         TEXT NOT NULL,
+		path       TEXT NOT NULL,
+		session_id TEXT NOT NULL DEFAULT '',
+		old_status TEXT NOT NULL,
+		new_status TEXT NOT NULL,
+		cause      TEXT NOT NULL,
+		event      TEXT NOT NULL DEFAULT '',
+		timestamp  TEXT NOT NULL
+	);
+	CREATE INDEX idx_status_transitions_path ON status_transitions (path, timestamp);
+	CREATE INDEX idx_status_transitions_timestamp ON status_transitions (timestamp);`,
+
+	// 3: key agent statuses and last messages by session instead of path
+	`CREATE TABLE agent_status_by_key (
+		key           TEXT PRIMARY KEY, -- Session ID, or path for entries without one
+		path          TEXT NOT NULL,
+		status        TEXT NOT NULL,
+		session_id    TEXT NOT NULL DEFAULT '',
+		last_activity TEXT NOT NULL,
+		data          TEXT NOT NULL -- Full AgentStatus as JSON
+	);
+	INSERT OR REPLACE INTO agent_status_by_key (key, path, status, session_id, last_activity, data)
+		SELECT CASE WHEN session_id != '' THEN session_id ELSE path END, path, status, session_id, last_activity, data
+		FROM agent_status ORDER BY rowid;
+	DROP TABLE agent_status;
+	ALTER TABLE agent_status_by_key RENAME TO agent_status;
+	CREATE INDEX idx_agent_status_path ON agent_status (path);
+	ALTER TABLE last_messages RENAME COLUMN path TO key;`,
+
+	// 4: keep minion messages after delivery, with their lifecycle
+	`ALTER TABLE minion_messages ADD COLUMN status TEXT NOT NULL DEFAULT 'queued';
+	ALTER TABLE minion_messages ADD COLUMN data TEXT; -- Full MinionMessage as JSON, NULL for rows queued before this migration
+	CREATE INDEX idx_minion_messages_status ON minion_messages (status);`,
+
+	// 5: minion registry
+	`CREATE TABLE minions (
+		id         TEXT PRIMARY KEY,
+		path       TEXT NOT NULL,
+		started_at TEXT NOT NULL,
+		data       TEXT NOT NULL -- Full Minion as JSON
+	);
+	CREATE INDEX idx_minions_path ON minions (path);`,
+
+	// 6: message schedules
+	`CREATE TABLE schedules (
+		id         TEXT PRIMARY KEY,
+		path       TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		data       TEXT NOT NULL -- Full MessageSchedule as JSON
+	);
+	CREATE INDEX idx_schedules_path ON schedules (path);`,
+}
+
+// sqlQuerier is satisfied by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqliteStore keeps all state, including system actions and last messages, in an
+// embedded SQLite database shared by the server, hook and minion processes
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(configDir string) (*sqliteStore, error) {
+	// Immediate transactions take the write lock up front, so concurrent
+	// read-modify-write cycles from hook processes queue up instead of failing
+	dsn := "file:" + filepath.Join(configDir, "state.db") +
+		"?_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	// One connection per process; other processes are serialized by SQLite's own locking
+	db.SetMaxOpenConns(1)
+
+	store := &sqliteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies any migrations the database hasn't seen yet
+func (s *sqliteStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	for i, migration := range sqliteMigrations {
+		version := i + 1
+		err := s.withTx(func(tx *sql.Tx) error {
+			// Another process may have applied it while we waited for the lock
+			var applied int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&applied); err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+
+			if _, err := tx.Exec(migration); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, formatTime(time.Now()))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// withTx runs fn in a transaction, committing only if it succeeds
+func (s *sqliteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetRepositories() ([]Repository, error) {
+	return queryRepositories(s.db)
+}
+
+func (s *sqliteStore) SaveRepositories(repos []Repository) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return replaceRepositories(tx, repos)
+	})
+}
+
+func (s *sqliteStore) UpdateRepositories(update func(repos []Repository) ([]Repository, error)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		repos, err := queryRepositories(tx)
+		if err != nil {
+			return err
+		}
+
+		repos, err = update(repos)
+		if err != nil {
+			return err
+		}
+
+		return replaceRepositories(tx, repos)
+	})
+}
+
+func queryRepositories(q sqlQuerier) ([]Repository, error) {
+	rows, err := q.Query(`SELECT id, path, name, created_at, updated_at FROM repositories ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repositories: %w", err)
+	}
+	defer rows.Close()
+
+	repos := []Repository{}
+	for rows.Next() {
+		var repo Repository
+		var createdAt, updatedAt string
+		if err := rows.Scan(&repo.ID, &repo.Path, &repo.Name, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read repository: %w", err)
+		}
+		repo.CreatedAt = parseTime(createdAt)
+		repo.UpdatedAt = parseTime(updatedAt)
+		repos = append(repos, repo)
+	}
+	return repos, rows.Err()
+}
+
+func replaceRepositories(q sqlQuerier, repos []Repository) error {
+	if _, err := q.Exec(`DELETE FROM repositories`); err != nil {
+		return fmt.Errorf("failed to clear repositories: %w", err)
+	}
+
+	for _, repo := range repos {
+		if _, err := q.Exec(`INSERT INTO repositories (id, path, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			repo.ID, repo.Path, repo.Name, formatTime(repo.CreatedAt), formatTime(repo.UpdatedAt)); err != nil {
+			return fmt.Errorf("failed to save repository %s: %w", repo.Path, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetAgentStatus() ([]AgentStatus, error) {
+	return queryAgentStatus(s.db)
+}
+
+func (s *sqliteStore) SaveAgentStatus(statuses []AgentStatus) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return replaceAgentStatus(tx, statuses)
+	})
+}
+
+func (s *sqliteStore) UpdateAgentStatus(update func(statuses []AgentStatus) ([]AgentStatus, bool)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		statuses, err := queryAgentStatus(tx)
+		if err != nil {
+			return err
+		}
+
+		statuses, changed := update(statuses)
+		if !changed {
+			return nil
+		}
+
+		return replaceAgentStatus(tx, statuses)
+	})
+}
+
+// StatusVersion is a counter bumped by every agent status write
+func (s *sqliteStore) StatusVersion() (string, error) {
+	var version int64
+	if err := s.db.QueryRow(`SELECT value FROM meta WHERE key = 'status_version'`).Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to read status version: %w", err)
+	}
+	return strconv.FormatInt(version, 10), nil
+}
+
+// jsonImported reports whether JSON state was imported into the database. Databases
+// from before imports were recorded count as imported once they hold any state.
+func (s *sqliteStore) jsonImported() (bool, error) {
+	var imported bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM meta WHERE key = 'json_imported')
+		OR EXISTS (SELECT 1 FROM repositories)
+		OR EXISTS (SELECT 1 FROM agent_status)
+		OR EXISTS (SELECT 1 FROM minion_messages)
+		OR EXISTS (SELECT 1 FROM status_transitions)
+		OR EXISTS (SELECT 1 FROM minions)
+		OR EXISTS (SELECT 1 FROM schedules)`).Scan(&imported); err != nil {
+		return false, fmt.Errorf("failed to check for imported JSON state: %w", err)
+	}
+	return imported, nil
+}
+
+// markJSONImported records that JSON state was imported, so later switches skip it
+func (s *sqliteStore) markJSONImported() error {
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO meta (key, value) VALUES ('json_imported', 1)`); err != nil {
+		return fmt.Errorf("failed to record JSON import: %w", err)
+	}
+	return nil
+}
+
+func queryAgentStatus(q sqlQuerier) ([]AgentStatus, error) {
+	rows, err := q.Query(`SELECT data FROM agent_status ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []AgentStatus{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read agent status: %w", err)
+		}
+
+		var status AgentStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			return nil, fmt.Errorf("failed to parse agent status: %w", err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+func replaceAgentStatus(q sqlQuerier, statuses []AgentStatus) error {
+	if _, err := q.Exec(`DELETE FROM agent_status`); err != nil {
+		return fmt.Errorf("failed to clear agent status: %w", err)
+	}
+
+	for _, status := range statuses {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return fmt.Errorf("failed to marshal agent status: %w", err)
+		}
+
+		if _, err := q.Exec(`INSERT OR REPLACE INTO agent_status (key, path, status, session_id, last_activity, data) VALUES (?, ?, ?, ?, ?, ?)`,
+			status.Key(), status.Path, status.Status, status.SessionID, formatTime(status.LastActivity), string(data)); err != nil {
+			return fmt.Errorf("failed to save agent status for %s: %w", status.Path, err)
+		}
+	}
+
+	if _, err := q.Exec(`UPDATE meta SET value = value + 1 WHERE key = 'status_version'`); err != nil {
+		return fmt.Errorf("failed to bump status version: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetMinionMessages(path string) ([]MinionMessage, error) {
+	return queryMinionMessages(s.db, path)
+}
+
+func (s *sqliteStore) AddMinionMessage(message MinionMessage) error {
+	return insertMinionMessage(s.db, message)
+}
+
+func (s *sqliteStore) UpdateMinionMessages(path string, update func(messages []MinionMessage) ([]MinionMessage, bool)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		messages, err := queryMinionMessages(tx, path)
+		if err != nil {
+			return err
+		}
+
+		messages, changed := update(messages)
+		if !changed {
+			return nil
+		}
+
+		if _, err := tx.Exec(`DELETE FROM minion_messages WHERE path = ?`, path); err != nil {
+			return fmt.Errorf("failed to clear minion messages: %w", err)
+		}
+		for _, message := range messages {
+			if err := insertMinionMessage(tx, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queryMinionMessages reads the messages for a path, or for every path when it is empty
+func queryMinionMessages(q sqlQuerier, path string) ([]MinionMessage, error) {
+	query := `SELECT id, path, message, timestamp, data FROM minion_messages`
+	var args []interface{}
+	if path != "" {
+		query += ` WHERE path = ?`
+		args = append(args, path)
+	}
+
+	rows, err := q.Query(query+` ORDER BY seq`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minion messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []MinionMessage{}
+	for rows.Next() {
+		var message MinionMessage
+		var timestamp string
+		var data sql.NullString
+		if err := rows.Scan(&message.ID, &message.Path, &message.Message, &timestamp, &data); err != nil {
+			return nil, fmt.Errorf("failed to read minion message: %w", err)
+		}
+		message.Timestamp = parseTime(timestamp)
+
+		if data.Valid {
+			if err := json.Unmarshal([]byte(data.String), &message); err != nil {
+				return nil, fmt.Errorf("failed to parse minion message: %w", err)
+			}
+		}
+		messages = append(messages, message.withDefaults())
+	}
+	return messages, rows.Err()
+}
+
+func insertMinionMessage(q sqlQuerier, message MinionMessage) error {
+	message = message.withDefaults()
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal minion message: %w", err)
+	}
+
+	if _, err := q.Exec(`INSERT INTO minion_messages (id, path, message, timestamp, status, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		message.ID, message.Path, message.Message, formatTime(message.Timestamp), message.Status, string(data)); err != nil {
+		return fmt.Errorf("failed to save minion message: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) AddSystemAction(action SystemAction) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO system_actions (id, type, description, command, timestamp) VALUES (?, ?, ?, ?, ?)`,
+			action.ID, action.Type, action.Description, action.Command, formatTime(action.Timestamp)); err != nil {
+			return fmt.Errorf("failed to save system action: %w", err)
+		}
+
+		// Capped like the JSON store, since nothing reads older actions
+		if _, err := tx.Exec(`DELETE FROM system_actions WHERE seq NOT IN (SELECT seq FROM system_actions ORDER BY seq DESC LIMIT ?)`,
+			maxStoredSystemActions); err != nil {
+			return fmt.Errorf("failed to prune system actions: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) GetSystemActions(limit int) ([]SystemAction, error) {
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+
+	// Newest first to apply the limit, then reversed to match the oldest-first contract
+	rows, err := s.db.Query(`SELECT id, type, description, command, timestamp FROM system_actions ORDER BY seq DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []SystemAction{}
+	for rows.Next() {
+		var action SystemAction
+		var timestamp string
+		if err := rows.Scan(&action.ID, &action.Type, &action.Description, &action.Command, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to read system action: %w", err)
+		}
+		action.Timestamp = parseTime(timestamp)
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(actions)-1; i < j; i, j = i+1, j-1 {
+		actions[i], actions[j] = actions[j], actions[i]
+	}
+	return actions, nil
+}
+
+func (s *sqliteStore) AddStatusTransitions(transitions []StatusTransition) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, t := range transitions {
+			if _, err := tx.Exec(`INSERT INTO status_transitions (id, path, session_id, old_status, new_status, cause, event, timestamp)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				t.ID, t.Path, t.SessionID, t.OldStatus, t.NewStatus, t.Cause, t.Event, formatTime(t.Timestamp)); err != nil {
+				return fmt.Errorf("failed to save status transition: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) GetStatusTransitions(path string, since time.Time) ([]StatusTransition, error) {
+	query := `SELECT id, path, session_id, old_status, new_status, cause, event, timestamp
+		FROM status_transitions WHERE timestamp >= ?`
+	args := []interface{}{formatTime(since)}
+	if path != "" {
+		query += ` AND path = ?`
+		args = append(args, path)
+	}
+	query += ` ORDER BY seq`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status transitions: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := []StatusTransition{}
+	for rows.Next() {
+		var t StatusTransition
+		var timestamp string
+		if err := rows.Scan(&t.ID, &t.Path, &t.SessionID, &t.OldStatus, &t.NewStatus, &t.Cause, &t.Event, &timestamp); err != nil {
+			return nil, fmt.Errorf("failed to read status transition: %w", err)
+		}
+		t.Timestamp = parseTime(timestamp)
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+func (s *sqliteStore) PruneStatusTransitions(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM status_transitions WHERE timestamp < ?`, formatTime(before)); err != nil {
+		return fmt.Errorf("failed to prune status transitions: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) SaveLastMessage(key, message, fullMessage string) error {
+	if _, err := s.db.Exec(`INSERT INTO last_messages (key, message, full_message, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET message = excluded.message, full_message = excluded.full_message, updated_at = excluded.updated_at`,
+		key, message, fullMessage, formatTime(time.Now())); err != nil {
+		return fmt.Errorf("failed to save last message: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetLastMessages() (map[string]LastMessage, error) {
+	rows, err := s.db.Query(`SELECT key, message, full_message FROM last_messages`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make(map[string]LastMessage)
+	for rows.Next() {
+		var key string
+		var message LastMessage
+		if err := rows.Scan(&key, &message.Message, &message.FullMessage); err != nil {
+			return nil, fmt.Errorf("failed to read last message: %w", err)
+		}
+		messages[key] = message
+	}
+	return messages, rows.Err()
+}
+
+func (s *sqliteStore) SaveMinion(minion Minion) error {
+	return upsertMinion(s.db, minion)
+}
+
+func (s *sqliteStore) DeleteMinion(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM minions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete minion: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetMinion(id string) (*Minion, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM minions WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("minion %w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minion: %w", err)
+	}
+
+	var minion Minion
+	if err := json.Unmarshal([]byte(data), &minion); err != nil {
+		return nil, fmt.Errorf("failed to parse minion: %w", err)
+	}
+	return &minion, nil
+}
+
+func (s *sqliteStore) GetMinions() ([]Minion, error) {
+	rows, err := s.db.Query(`SELECT data FROM minions ORDER BY started_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minions: %w", err)
+	}
+	defer rows.Close()
+
+	minions := []Minion{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read minion: %w", err)
+		}
+
+		var minion Minion
+		if err := json.Unmarshal([]byte(data), &minion); err != nil {
+			return nil, fmt.Errorf("failed to parse minion: %w", err)
+		}
+		minions = append(minions, minion)
+	}
+	return minions, rows.Err()
+}
+
+func upsertMinion(q sqlQuerier, minion Minion) error {
+	data, err := json.Marshal(minion)
+	if err != nil {
+		return fmt.Errorf("failed to marshal minion: %w", err)
+	}
+
+	if _, err := q.Exec(`INSERT OR REPLACE INTO minions (id, path, started_at, data) VALUES (?, ?, ?, ?)`,
+		minion.ID, minion.Path, formatTime(minion.StartedAt), string(data)); err != nil {
+		return fmt.Errorf("failed to save minion %s: %w", minion.ID, err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) SaveSchedule(schedule MessageSchedule) error {
+	return upsertSchedule(s.db, schedule)
+}
+
+func (s *sqliteStore) UpdateSchedule(id string, update func(schedule *MessageSchedule) bool) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		schedule, err := querySchedule(tx, id)
+		if err != nil {
+			return err
+		}
+		if !update(schedule) {
+			return nil
+		}
+		return upsertSchedule(tx, *schedule)
+	})
+}
+
+func (s *sqliteStore) DeleteSchedule(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM schedules WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetSchedule(id string) (*MessageSchedule, error) {
+	return querySchedule(s.db, id)
+}
+
+func (s *sqliteStore) GetSchedules() ([]MessageSchedule, error) {
+	rows, err := s.db.Query(`SELECT data FROM schedules ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []MessageSchedule{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read schedule: %w", err)
+		}
+
+		var schedule MessageSchedule
+		if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+			return nil, fmt.Errorf("failed to parse schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func querySchedule(q sqlQuerier, id string) (*MessageSchedule, error) {
+	var data string
+	err := q.QueryRow(`SELECT data FROM schedules WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("schedule %w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedule: %w", err)
+	}
+
+	var schedule MessageSchedule
+	if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+func upsertSchedule(q sqlQuerier, schedule MessageSchedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	if _, err := q.Exec(`INSERT OR REPLACE INTO schedules (id, path, created_at, data) VALUES (?, ?, ?, ?)`,
+		schedule.ID, schedule.Path, formatTime(schedule.CreatedAt), string(data)); err != nil {
+		return fmt.Errorf("failed to save schedule %s: %w", schedule.ID, err)
+	}
+	return nil
+}
+
+// sqliteTimeFormat is RFC 3339 with a fixed number of fractional digits, so
+// timestamps stored as text compare correctly as strings
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
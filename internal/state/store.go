@@ -0,0 +1,212 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+// Errors wrapped by lookups and changes of stored state, for callers to tell apart
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// Number of system actions a store keeps; older ones are dropped as new ones come in
+const maxStoredSystemActions = 50
+
+// Store persists dashboard state. The Manager layers watchers, callbacks and live
+// minion delivery on top of whichever store is configured.
+type Store interface {
+	GetRepositories() ([]Repository, error)
+	SaveRepositories(repos []Repository) error
+	// UpdateRepositories runs a read-modify-write of the repositories atomically
+	UpdateRepositories(update func(repos []Repository) ([]Repository, error)) error
+
+	GetAgentStatus() ([]AgentStatus, error)
+	SaveAgentStatus(statuses []AgentStatus) error
+	// UpdateAgentStatus runs a read-modify-write of the statuses atomically,
+	// saving only when update reports a change
+	UpdateAgentStatus(update func(statuses []AgentStatus) ([]AgentStatus, bool)) error
+	// StatusVersion changes whenever any process saves agent statuses
+	StatusVersion() (string, error)
+
+	// GetMinionMessages returns messages in every state, oldest first; an empty path matches all minions
+	GetMinionMessages(path string) ([]MinionMessage, error)
+	AddMinionMessage(message MinionMessage) error
+	// UpdateMinionMessages runs a read-modify-write of a path's messages atomically,
+	// saving only when update reports a change
+	UpdateMinionMessages(path string, update func(messages []MinionMessage) ([]MinionMessage, bool)) error
+
+	// AddSystemAction records an action, keeping only the last maxStoredSystemActions
+	AddSystemAction(action SystemAction) error
+	// GetSystemActions returns up to limit of the most recent actions, oldest first
+	GetSystemActions(limit int) ([]SystemAction, error)
+
+	AddStatusTransitions(transitions []StatusTransition) error
+	// GetStatusTransitions returns transitions since a time, oldest first; an empty path matches all agents
+	GetStatusTransitions(path string, since time.Time) ([]StatusTransition, error)
+	// PruneStatusTransitions deletes transitions older than a time
+	PruneStatusTransitions(before time.Time) error
+
+	// Last messages are keyed by AgentStatus.Key
+	SaveLastMessage(key, message, fullMessage string) error
+	GetLastMessages() (map[string]LastMessage, error)
+
+	// SaveMinion adds a minion to the registry or replaces the entry with its ID
+	SaveMinion(minion Minion) error
+	DeleteMinion(id string) error
+	// GetMinion returns an error wrapping ErrNotFound for an unknown ID
+	GetMinion(id string) (*Minion, error)
+	// GetMinions returns every registered minion, including ones whose process has died
+	GetMinions() ([]Minion, error)
+
+	// SaveSchedule adds a schedule or replaces the one with its ID
+	SaveSchedule(schedule MessageSchedule) error
+	// UpdateSchedule runs a read-modify-write of a schedule atomically, saving only
+	// when update reports a change. update must not call back into the store.
+	UpdateSchedule(id string, update func(schedule *MessageSchedule) bool) error
+	DeleteSchedule(id string) error
+	// GetSchedule returns an error wrapping ErrNotFound for an unknown ID
+	GetSchedule(id string) (*MessageSchedule, error)
+	GetSchedules() ([]MessageSchedule, error)
+
+	Close() error
+}
+
+// LastMessage is the latest transcript message seen for an agent
+type LastMessage struct {
+	Message     string
+	FullMessage string
+}
+
+// OpenStore opens the storage backend selected in the dashboard settings
+func OpenStore(configDir string) (Store, error) {
+	settings, err := config.LoadSettings(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	switch settings.StorageBackend {
+	case config.StorageSQLite:
+		return openSQLiteStore(configDir)
+	default:
+		return newJSONStore(configDir)
+	}
+}
+
+// SetStorageBackend switches the backend for all dashboard processes. Switching to
+// SQLite for the first time imports the existing JSON state.
+func SetStorageBackend(configDir, backend string) error {
+	settings, err := config.LoadSettings(configDir)
+	if err != nil {
+		return err
+	}
+
+	if settings.StorageBackend == backend {
+		return nil
+	}
+
+	settings.StorageBackend = backend
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	if backend == config.StorageSQLite {
+		if err := importJSONState(configDir); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Switching storage backend to %s", backend)
+	return config.SaveSettings(configDir, settings)
+}
+
+// importJSONState copies JSON state into a new SQLite database
+func importJSONState(configDir string) error {
+	sqlite, err := openSQLiteStore(configDir)
+	if err != nil {
+		return err
+	}
+	defer sqlite.Close()
+
+	imported, err := sqlite.jsonImported()
+	if err != nil {
+		return err
+	}
+	if imported {
+		// Already imported on an earlier switch; don't clobber newer SQLite state
+		return nil
+	}
+
+	jsonStore, err := newJSONStore(configDir)
+	if err != nil {
+		return err
+	}
+	defer jsonStore.Close()
+
+	repos, err := jsonStore.GetRepositories()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON repositories: %w", err)
+	}
+	if err := sqlite.SaveRepositories(repos); err != nil {
+		return err
+	}
+
+	statuses, err := jsonStore.GetAgentStatus()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON agent status: %w", err)
+	}
+	if err := sqlite.SaveAgentStatus(statuses); err != nil {
+		return err
+	}
+
+	messages, err := jsonStore.getAllMinionMessages()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON minion messages: %w", err)
+	}
+	for _, message := range messages {
+		if err := sqlite.AddMinionMessage(message); err != nil {
+			return err
+		}
+	}
+
+	transitions, err := jsonStore.GetStatusTransitions("", time.Time{})
+	if err != nil {
+		return fmt.Errorf("failed to read JSON status history: %w", err)
+	}
+	if err := sqlite.AddStatusTransitions(transitions); err != nil {
+		return err
+	}
+
+	minions, err := jsonStore.GetMinions()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON minion registry: %w", err)
+	}
+	for _, minion := range minions {
+		if err := sqlite.SaveMinion(minion); err != nil {
+			return err
+		}
+	}
+
+	schedules, err := jsonStore.GetSchedules()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON schedules: %w", err)
+	}
+	for _, schedule := range schedules {
+		if err := sqlite.SaveSchedule(schedule); err != nil {
+			return err
+		}
+	}
+
+	if err := sqlite.markJSONImported(); err != nil {
+		return err
+	}
+
+	log.Printf("Imported %d repositories, %d agent statuses, %d minion messages, %d status transitions, %d minions and %d schedules into SQLite",
+		len(repos), len(statuses), len(messages), len(transitions), len(minions), len(schedules))
+	return nil
+}
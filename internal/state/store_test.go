@@ -0,0 +1,203 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+)
+
+// forEachBackend runs a test against an empty store of every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, backend := range []string{config.StorageJSON, config.StorageSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := OpenStore(newTestConfigDir(t, backend))
+			if err != nil {
+				t.Fatalf("failed to open store: %v", err)
+			}
+			defer store.Close()
+
+			test(t, store)
+		})
+	}
+}
+
+func TestStoreMinions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		started := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		first := Minion{ID: "minion_1", PID: 100, Path: "/repo", Command: []string{"claude"}, StartedAt: started}
+		second := Minion{ID: "minion_2", PID: 200, Path: "/repo-feature", Command: []string{"claude", "-c"}, StartedAt: started.Add(time.Minute)}
+
+		for _, minion := range []Minion{second, first} {
+			if err := store.SaveMinion(minion); err != nil {
+				t.Fatalf("SaveMinion(%s) = %v", minion.ID, err)
+			}
+		}
+
+		// Saving again replaces the entry
+		first.PID = 101
+		if err := store.SaveMinion(first); err != nil {
+			t.Fatalf("SaveMinion(%s) = %v", first.ID, err)
+		}
+
+		got, err := store.GetMinion(first.ID)
+		if err != nil {
+			t.Fatalf("GetMinion() = %v", err)
+		}
+		if got.PID != 101 || got.Path != "/repo" || len(got.Command) != 1 || !got.StartedAt.Equal(started) {
+			t.Errorf("GetMinion() = %+v, want %+v", *got, first)
+		}
+
+		minions, err := store.GetMinions()
+		if err != nil {
+			t.Fatalf("GetMinions() = %v", err)
+		}
+		if len(minions) != 2 {
+			t.Fatalf("GetMinions() returned %d minions, want 2", len(minions))
+		}
+
+		if err := store.DeleteMinion(first.ID); err != nil {
+			t.Fatalf("DeleteMinion() = %v", err)
+		}
+		if _, err := store.GetMinion(first.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetMinion() after delete = %v, want ErrNotFound", err)
+		}
+		if err := store.DeleteMinion(first.ID); err != nil {
+			t.Errorf("DeleteMinion() of a missing minion = %v", err)
+		}
+
+		minions, err = store.GetMinions()
+		if err != nil {
+			t.Fatalf("GetMinions() = %v", err)
+		}
+		if len(minions) != 1 || minions[0].ID != second.ID {
+			t.Errorf("GetMinions() after delete = %+v, want only %s", minions, second.ID)
+		}
+	})
+}
+
+func TestStoreSchedules(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		created := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		schedule := MessageSchedule{ID: "schedule_1", Path: "/repo", Message: "hi", Kind: ScheduleCron, Cron: "0 9 * * *", Enabled: true, CreatedAt: created}
+		if err := store.SaveSchedule(schedule); err != nil {
+			t.Fatalf("SaveSchedule() = %v", err)
+		}
+
+		err := store.UpdateSchedule(schedule.ID, func(schedule *MessageSchedule) bool {
+			schedule.Enabled = false
+			return true
+		})
+		if err != nil {
+			t.Fatalf("UpdateSchedule() = %v", err)
+		}
+
+		// An update reporting no change is not saved
+		err = store.UpdateSchedule(schedule.ID, func(schedule *MessageSchedule) bool {
+			schedule.Message = "unsaved"
+			return false
+		})
+		if err != nil {
+			t.Fatalf("UpdateSchedule() = %v", err)
+		}
+
+		got, err := store.GetSchedule(schedule.ID)
+		if err != nil {
+			t.Fatalf("GetSchedule() = %v", err)
+		}
+		if got.Enabled || got.Message != "hi" || got.Cron != schedule.Cron || !got.CreatedAt.Equal(created) {
+			t.Errorf("GetSchedule() = %+v, want %+v paused", *got, schedule)
+		}
+
+		schedules, err := store.GetSchedules()
+		if err != nil {
+			t.Fatalf("GetSchedules() = %v", err)
+		}
+		if len(schedules) != 1 || schedules[0].ID != schedule.ID {
+			t.Errorf("GetSchedules() = %+v, want only %s", schedules, schedule.ID)
+		}
+
+		if err := store.DeleteSchedule(schedule.ID); err != nil {
+			t.Fatalf("DeleteSchedule() = %v", err)
+		}
+		if _, err := store.GetSchedule(schedule.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetSchedule() after delete = %v, want ErrNotFound", err)
+		}
+		err = store.UpdateSchedule(schedule.ID, func(schedule *MessageSchedule) bool { return true })
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateSchedule() after delete = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreCapsSystemActions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		total := maxStoredSystemActions + 10
+		for i := 0; i < total; i++ {
+			action := SystemAction{ID: fmt.Sprintf("action_%d", i), Type: "test", Description: "test", Timestamp: time.Now()}
+			if err := store.AddSystemAction(action); err != nil {
+				t.Fatalf("AddSystemAction() = %v", err)
+			}
+		}
+
+		// Without a limit, everything the store still keeps
+		actions, err := store.GetSystemActions(0)
+		if err != nil {
+			t.Fatalf("GetSystemActions() = %v", err)
+		}
+		if len(actions) != maxStoredSystemActions {
+			t.Fatalf("store kept %d actions, want %d", len(actions), maxStoredSystemActions)
+		}
+		if first, last := actions[0].ID, actions[len(actions)-1].ID; first != fmt.Sprintf("action_%d", total-maxStoredSystemActions) || last != fmt.Sprintf("action_%d", total-1) {
+			t.Errorf("store kept actions %s to %s, want the most recent", first, last)
+		}
+	})
+}
+
+func TestSwitchingToSQLiteImportsJSONStateOnce(t *testing.T) {
+	configDir := newTestConfigDir(t, config.StorageJSON)
+
+	// No repositories, so only the other tables show that the import happened
+	jsonStore, err := newJSONStore(configDir)
+	if err != nil {
+		t.Fatalf("failed to open JSON store: %v", err)
+	}
+	transition := StatusTransition{ID: "transition_1", Path: "/repo", OldStatus: "running", NewStatus: "idle", Cause: "hook", Timestamp: time.Now()}
+	if err := jsonStore.AddStatusTransitions([]StatusTransition{transition}); err != nil {
+		t.Fatalf("AddStatusTransitions() = %v", err)
+	}
+	message := MinionMessage{ID: "msg_1", Path: "/repo", Message: "hi", Timestamp: time.Now(), Status: MessageQueued}
+	if err := jsonStore.AddMinionMessage(message); err != nil {
+		t.Fatalf("AddMinionMessage() = %v", err)
+	}
+	jsonStore.Close()
+
+	for _, backend := range []string{config.StorageSQLite, config.StorageJSON, config.StorageSQLite} {
+		if err := SetStorageBackend(configDir, backend); err != nil {
+			t.Fatalf("SetStorageBackend(%s) = %v", backend, err)
+		}
+	}
+
+	sqlite, err := openSQLiteStore(configDir)
+	if err != nil {
+		t.Fatalf("failed to open SQLite store: %v", err)
+	}
+	defer sqlite.Close()
+
+	transitions, err := sqlite.GetStatusTransitions("", time.Time{})
+	if err != nil {
+		t.Fatalf("GetStatusTransitions() = %v", err)
+	}
+	if len(transitions) != 1 {
+		t.Errorf("SQLite has %d status transitions, want 1", len(transitions))
+	}
+	messages, err := sqlite.GetMinionMessages("")
+	if err != nil {
+		t.Fatalf("GetMinionMessages() = %v", err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("SQLite has %d minion messages, want 1", len(messages))
+	}
+}
@@ -0,0 +1,146 @@
+package terminal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes terminal output to an asciicast v2 file as it happens. Each
+// event is written straight through, so a recording can be played while in progress.
+type Recorder struct {
+	file    *os.File
+	start   time.Time
+	pending []byte // Incomplete UTF-8 sequence held back until the rest arrives
+	failed  bool
+	closed  bool
+	mutex   sync.Mutex
+}
+
+// NewRecorder creates an asciicast file and writes its header
+func NewRecorder(path string, header Header) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	start := time.Now()
+	header.Version = 2
+	header.Timestamp = start.Unix()
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to marshal recording header: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write recording header: %w", err)
+	}
+
+	return &Recorder{file: file, start: start}, nil
+}
+
+// Write records an output event. It never fails, so a full disk can't stall the
+// agent's output; write errors are logged once and recording stops.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data := append(r.pending, p...)
+	r.pending = nil
+
+	// Asciicast events are JSON strings, so a rune split across reads waits for its tail
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+
+	if cut > 0 {
+		r.writeEvent("o", string(data[:cut]))
+	}
+	return len(p), nil
+}
+
+// Resize records a terminal size change
+func (r *Recorder) Resize(cols, rows int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes any held-back output and closes the file. Closing twice is a no-op.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	if len(r.pending) > 0 {
+		r.writeEvent("o", string(r.pending))
+		r.pending = nil
+	}
+	r.closed = true
+	return r.file.Close()
+}
+
+// writeEvent appends one [time, type, data] line. The caller must hold the mutex.
+func (r *Recorder) writeEvent(eventType, data string) {
+	if r.failed || r.closed {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, eventType, data})
+	if err == nil {
+		_, err = r.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		log.Printf("Failed to write recording, stopping: %v", err)
+		r.failed = true
+	}
+}
+
+// ReadHeader reads the header line of an asciicast file
+func ReadHeader(path string) (*Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse recording header: %w", err)
+	}
+	return &header, nil
+}
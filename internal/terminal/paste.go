@@ -0,0 +1,63 @@
+package terminal
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Sequences a program prints to turn bracketed paste mode on and off, and the
+// markers a terminal wraps pasted text in while it is on
+var (
+	bracketedPasteOn  = []byte("\x1b[?2004h")
+	bracketedPasteOff = []byte("\x1b[?2004l")
+	pasteStart        = []byte("\x1b[200~")
+	pasteEnd          = []byte("\x1b[201~")
+)
+
+// PasteMode follows whether a program has turned on bracketed paste by watching
+// its output. It is safe for concurrent use.
+type PasteMode struct {
+	enabled bool
+	tail    []byte // End of the last write, in case a sequence is split across writes
+	mutex   sync.Mutex
+}
+
+// Write scans program output for the sequences that switch bracketed paste
+func (m *PasteMode) Write(p []byte) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data := append(m.tail, p...)
+	on := bytes.LastIndex(data, bracketedPasteOn)
+	off := bytes.LastIndex(data, bracketedPasteOff)
+	if on > off {
+		m.enabled = true
+	} else if off > on {
+		m.enabled = false
+	}
+
+	keep := len(bracketedPasteOn) - 1
+	if len(data) < keep {
+		keep = len(data)
+	}
+	m.tail = append([]byte{}, data[len(data)-keep:]...)
+	return len(p), nil
+}
+
+// Enabled reports whether the program last turned bracketed paste on
+func (m *PasteMode) Enabled() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.enabled
+}
+
+// BracketedPaste wraps text in paste markers, dropping any end marker inside it so
+// the text can't break out of the paste
+func BracketedPaste(text []byte) []byte {
+	text = bytes.ReplaceAll(text, pasteEnd, nil)
+
+	pasted := make([]byte, 0, len(pasteStart)+len(text)+len(pasteEnd))
+	pasted = append(pasted, pasteStart...)
+	pasted = append(pasted, text...)
+	return append(pasted, pasteEnd...)
+}
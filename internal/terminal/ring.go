@@ -0,0 +1,67 @@
+package terminal
+
+import "sync"
+
+// DefaultScrollback is how much recent terminal output is kept for replay
+const DefaultScrollback = 256 * 1024
+
+// RingBuffer keeps the most recent bytes written to it. It is safe for concurrent use.
+type RingBuffer struct {
+	data  []byte
+	start int // Index of the oldest byte
+	size  int // Number of bytes held
+	mutex sync.Mutex
+}
+
+// NewRingBuffer creates a buffer holding up to capacity bytes
+func NewRingBuffer(capacity int) *RingBuffer {
+	return &RingBuffer{data: make([]byte, capacity)}
+}
+
+// Write appends p, overwriting the oldest bytes once the buffer is full
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	n := len(p)
+	capacity := len(r.data)
+	if n >= capacity {
+		// Only the tail of a large write fits
+		copy(r.data, p[n-capacity:])
+		r.start = 0
+		r.size = capacity
+		return n, nil
+	}
+
+	end := (r.start + r.size) % capacity
+	copied := copy(r.data[end:], p)
+	copy(r.data, p[copied:])
+
+	r.size += n
+	if r.size > capacity {
+		r.start = (r.start + r.size - capacity) % capacity
+		r.size = capacity
+	}
+	return n, nil
+}
+
+// Bytes returns a copy of the buffered output, oldest first
+func (r *RingBuffer) Bytes() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	out := make([]byte, r.size)
+	copied := copy(out, r.data[r.start:min(r.start+r.size, len(r.data))])
+	copy(out[copied:], r.data[:r.size-copied])
+	return out
+}
+
+// Reset replaces the buffered output with p
+func (r *RingBuffer) Reset(p []byte) {
+	r.mutex.Lock()
+	r.start = 0
+	r.size = 0
+	r.mutex.Unlock()
+
+	r.Write(p)
+}
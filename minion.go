@@ -0,0 +1,535 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+
+	"coding-agent-dashboard/internal/config"
+	"coding-agent-dashboard/internal/fsutil"
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/process"
+	"coding-agent-dashboard/internal/state"
+)
+
+// How often a minion without a server connection polls the message files and retries the socket
+const (
+	minionPollInterval   = 500 * time.Millisecond
+	minionRedialInterval = 5 * time.Second
+)
+
+// Terminal size of a headless minion until a browser viewer resizes it
+const (
+	headlessCols = 120
+	headlessRows = 40
+)
+
+// minionPIDEnv tells hooks run by the wrapped Claude process which minion owns their session
+const minionPIDEnv = "CODING_AGENT_DASHBOARD_MINION_PID"
+
+func handleMinionMode() error {
+	// Create debug log file for minion mode
+	debugFile, err := os.OpenFile("/tmp/minion-debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		defer debugFile.Close()
+		log.SetOutput(debugFile)
+	} else {
+		// Fallback to discard if debug file can't be created
+		log.SetOutput(io.Discard)
+	}
+
+	// Get command arguments (everything after the --minion flag)
+	args := flag.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("minion mode requires at least one command argument")
+	}
+
+	// Get current working directory to identify this minion
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	workingDir = fsutil.CanonicalPath(workingDir)
+
+	// Initialize config directory and state manager for message watching
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	stateManager, err := state.NewManager(configDir, true)
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+	defer stateManager.Close()
+
+	// Register so messages can be sent to this minion in particular
+	self := state.Minion{
+		ID:        minionIdentity(),
+		PID:       os.Getpid(),
+		Path:      workingDir,
+		Command:   args,
+		StartedAt: time.Now(),
+	}
+	if info, err := process.Get(self.PID); err == nil {
+		self.PIDStartTime = info.StartTime
+	}
+	if err := stateManager.RegisterMinion(self); err != nil {
+		log.Printf("Failed to register minion: %v", err)
+	}
+	defer stateManager.UnregisterMinion(self.ID)
+
+	// Create command with the first argument as the command and rest as args
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", minionPIDEnv, os.Getpid()))
+
+	// Check if stdin is available (not a terminal or has data)
+	stat, err := os.Stdin.Stat()
+	stdinIsTerminal := !*headlessMinion && (err != nil || (stat.Mode()&os.ModeCharDevice) != 0)
+
+	// A headless minion has no terminal of its own but still gives the agent a PTY,
+	// which the dashboard's web terminal drives
+	usePTY := stdinIsTerminal || *headlessMinion
+
+	var stdinPipe io.WriteCloser
+	var ptyMaster *os.File
+
+	// Everything the agent prints is also kept for the dashboard's terminal view
+	agentTerminal := newMinionTerminal()
+
+	if *recordMinion {
+		// Piped output has no terminal size, so record it at the asciicast default
+		width, height := 80, 24
+		if *headlessMinion {
+			width, height = headlessCols, headlessRows
+		} else if ws, err := pty.GetsizeFull(os.Stdin); err == nil && ws.Cols > 0 && ws.Rows > 0 {
+			width, height = int(ws.Cols), int(ws.Rows)
+		}
+
+		recorder, recording, err := stateManager.StartRecording(workingDir, strings.Join(args, " "), width, height)
+		if err != nil {
+			log.Printf("Recording disabled: %v", err)
+		} else {
+			log.Printf("Recording terminal to %s", recording.ID)
+			agentTerminal.recorder = recorder
+			defer recorder.Close()
+		}
+	}
+
+	if *headlessMinion {
+		ptyMaster, err = pty.StartWithSize(cmd, &pty.Winsize{Cols: headlessCols, Rows: headlessRows})
+		if err != nil {
+			return fmt.Errorf("failed to create pty: %w", err)
+		}
+		defer ptyMaster.Close()
+		agentTerminal.ptyMaster = ptyMaster
+
+		// Output only goes to the dashboard
+		go io.Copy(agentTerminal, ptyMaster)
+		stdinPipe = ptyMaster
+	} else if stdinIsTerminal {
+		// For terminal mode, create a pty so we can inject messages
+		ptyMaster, err = pty.Start(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to create pty: %w", err)
+		}
+		defer ptyMaster.Close()
+
+		// Set the pty size to match the current terminal; forwardSignals keeps it in step
+		if ws, err := pty.GetsizeFull(os.Stdin); err == nil {
+			pty.Setsize(ptyMaster, ws)
+		}
+		agentTerminal.ptyMaster = ptyMaster
+
+		// Put the real terminal in raw mode to properly forward key sequences
+		oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer term.Restore(int(os.Stdin.Fd()), oldState)
+
+		// Forward pty output to real stdout/stderr and the dashboard
+		go io.Copy(io.MultiWriter(os.Stdout, agentTerminal), ptyMaster)
+
+		// Use ptyMaster as our message injection point; real stdin is forwarded to it
+		// once the agent's input is set up below
+		stdinPipe = ptyMaster
+	} else {
+		// For piped mode, use our pipe for message injection
+		stdinPipe, err = cmd.StdinPipe()
+		if err != nil {
+			return fmt.Errorf("failed to create stdin pipe: %w", err)
+		}
+
+		// Connect stdout and stderr for transparency, teeing them to the dashboard
+		cmd.Stdout = io.MultiWriter(os.Stdout, agentTerminal)
+		cmd.Stderr = io.MultiWriter(os.Stderr, agentTerminal)
+	}
+	agentTerminal.input = stdinPipe
+
+	if stdinIsTerminal {
+		// Forward real stdin to pty (in background), sharing it with injected messages
+		go io.Copy(agentInput{agentTerminal}, os.Stdin)
+	}
+
+	// Start the command (only for non-pty mode, pty.Start already started it)
+	if !usePTY {
+		isolateProcessGroup(cmd)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to start command: %w", err)
+		}
+	}
+
+	// Don't manipulate stdin in terminal mode
+
+	// Debug: log that process started
+	if debugFile, err := os.OpenFile("/tmp/minion-debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		debugFile.WriteString(fmt.Sprintf("[%s] Started process: %s (PID: %d)\n", time.Now().Format("15:04:05"), args[0], cmd.Process.Pid))
+		debugFile.WriteString(fmt.Sprintf("[%s] Sent initial newline to Claude\n", time.Now().Format("15:04:05")))
+		debugFile.Close()
+	}
+
+	// Create channels for coordination
+	stdinDone := make(chan bool)
+	processExit := make(chan error, 1)
+	stopTicker := make(chan bool)
+	var processRunning bool = true
+	var processRunningMutex sync.RWMutex
+
+	// Relay signals and terminal resizes to the agent while it runs
+	go forwardSignals(cmd, agentTerminal, stdinIsTerminal, stopTicker)
+	controller := newMinionController(cmd, agentTerminal, usePTY)
+
+	// Copy from os.Stdin to the command's stdin in a goroutine
+	go func() {
+		defer close(stdinDone)
+		if !usePTY {
+			// For piped stdin, copy everything
+			io.Copy(agentInput{agentTerminal}, os.Stdin)
+		}
+		// For terminal stdin, don't copy anything but keep pipe open for message injection
+	}()
+
+	// Watch for minion messages and forward them to stdin
+	messages := make(chan *state.MinionMessage)
+	injection := injectionSettings(configDir, args[0])
+	go watchMinionMessages(stateManager, self, agentTerminal, controller, messages, stopTicker)
+	go func() {
+		// Create debug log file
+		var debugLog *os.File
+		debugLog, err := os.OpenFile("/tmp/minion-debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			defer debugLog.Close()
+			debugLog.WriteString(fmt.Sprintf("[%s] Started minion message watcher for working dir: %s\n", time.Now().Format("15:04:05"), workingDir))
+			debugLog.WriteString(fmt.Sprintf("[%s] Config dir: %s\n", time.Now().Format("15:04:05"), configDir))
+			debugLog.WriteString(fmt.Sprintf("[%s] Entering message loop\n", time.Now().Format("15:04:05")))
+		}
+
+		for {
+			select {
+			case message := <-messages:
+				if message != nil {
+					if debugLog != nil {
+						debugLog.WriteString(fmt.Sprintf("[%s] Found message: %s\n", time.Now().Format("15:04:05"), message.Message))
+					}
+
+					// Check if process is still running before writing to stdin
+					processRunningMutex.RLock()
+					running := processRunning
+					processRunningMutex.RUnlock()
+
+					if !running {
+						if debugLog != nil {
+							debugLog.WriteString(fmt.Sprintf("[%s] Process not running, leaving message queued\n", time.Now().Format("15:04:05")))
+						}
+						reportMinionMessage(stateManager, agentTerminal, message, fmt.Errorf("agent is not running"))
+						continue
+					}
+
+					// Send message to child process stdin (only if we have a pipe), holding
+					// off other input until the whole message is typed and submitted
+					if stdinPipe != nil {
+						err := agentTerminal.withInput(func(input io.Writer) error {
+							return injectMessage(input, message.Message, injection, agentTerminal.pasteMode.Enabled())
+						})
+						if err != nil {
+							if debugLog != nil {
+								debugLog.WriteString(fmt.Sprintf("[%s] Error writing to stdin (pipe may be closed): %v\n", time.Now().Format("15:04:05"), err))
+							}
+							// Don't return here - the process might still be running, just stdin closed
+							reportMinionMessage(stateManager, agentTerminal, message, fmt.Errorf("failed to write to agent: %w", err))
+							continue
+						}
+						if debugLog != nil {
+							debugLog.WriteString(fmt.Sprintf("[%s] Successfully sent message %q + submit to stdin\n", time.Now().Format("15:04:05"), message.Message))
+						}
+						reportMinionMessage(stateManager, agentTerminal, message, nil)
+					} else {
+						if debugLog != nil {
+							debugLog.WriteString(fmt.Sprintf("[%s] No stdin pipe available for message injection\n", time.Now().Format("15:04:05")))
+						}
+						reportMinionMessage(stateManager, agentTerminal, message, fmt.Errorf("agent has no stdin to write to"))
+					}
+				}
+			case <-controller.exited:
+				if debugLog != nil {
+					debugLog.WriteString(fmt.Sprintf("[%s] Message ticker exiting due to process exit\n", time.Now().Format("15:04:05")))
+				}
+				return
+			case <-stopTicker:
+				if debugLog != nil {
+					debugLog.WriteString(fmt.Sprintf("[%s] Message ticker stopping\n", time.Now().Format("15:04:05")))
+				}
+				return
+			}
+		}
+	}()
+
+	// Wait for the command to complete in a goroutine
+	go func() {
+		err := cmd.Wait()
+		close(controller.exited)
+		processExit <- err
+	}()
+
+	// Wait for process to exit (and stdin if it's not a terminal)
+	if usePTY {
+		// For terminal stdin, just wait for process to exit
+		err = <-processExit
+		// Process exited, mark it as not running
+		processRunningMutex.Lock()
+		processRunning = false
+		processRunningMutex.Unlock()
+
+		// Stop the message ticker first
+		close(stopTicker)
+		// Give ticker time to stop
+		time.Sleep(50 * time.Millisecond)
+		// Then close stdin pipe
+		stdinPipe.Close()
+	} else {
+		// For piped stdin, coordinate between process exit and stdin done
+		select {
+		case err = <-processExit:
+			// Process exited, mark it as not running
+			processRunningMutex.Lock()
+			processRunning = false
+			processRunningMutex.Unlock()
+
+			// Stop the message ticker first
+			close(stopTicker)
+			// Give ticker time to stop
+			time.Sleep(50 * time.Millisecond)
+			// Then close stdin pipe
+			stdinPipe.Close()
+			// Wait a bit for any remaining stdin data
+			select {
+			case <-stdinDone:
+			case <-time.After(100 * time.Millisecond):
+			}
+		case <-stdinDone:
+			// Stdin closed, mark process as not running
+			processRunningMutex.Lock()
+			processRunning = false
+			processRunningMutex.Unlock()
+
+			// Stop ticker and close the pipe to signal EOF to subprocess
+			close(stopTicker)
+			time.Sleep(50 * time.Millisecond)
+			stdinPipe.Close()
+			// Now wait for process to complete
+			err = <-processExit
+		}
+	}
+
+	// Claude skips its SessionEnd hook when it is killed, so retire its sessions here
+	exitCode := process.ExitCode(err)
+	if endErr := stateManager.MarkMinionSessionsExited(os.Getpid(), exitCode); endErr != nil {
+		log.Printf("Failed to retire sessions: %v", endErr)
+	}
+
+	if controller.restartRequested() {
+		log.Printf("Agent exited with code %d, restarting", exitCode)
+		return errMinionRestart
+	}
+
+	if err != nil {
+		// os.Exit skips deferred calls, so finish the recording, unregister and
+		// close the store first
+		if agentTerminal.recorder != nil {
+			agentTerminal.recorder.Close()
+		}
+		stateManager.UnregisterMinion(self.ID)
+		// Exit with the same exit code as the child process
+		if _, ok := err.(*exec.ExitError); ok {
+			stateManager.Close()
+			os.Exit(exitCode)
+		}
+		return fmt.Errorf("command execution failed: %w", err)
+	}
+
+	return nil
+}
+
+// watchMinionMessages feeds messages for a minion into the messages channel. While
+// the dashboard server is reachable messages are pushed over its socket; otherwise
+// the message files are polled and the socket is retried periodically.
+func watchMinionMessages(stateManager *state.Manager, self state.Minion, agentTerminal *minionTerminal, controller *minionController, messages chan<- *state.MinionMessage, stop <-chan bool) {
+	socketPath := ipc.SocketPath(stateManager.ConfigDir())
+
+	for {
+		if conn, err := ipc.Dial(socketPath); err == nil {
+			subscribe := ipc.SubscribePayload{Path: self.Path, MinionID: self.ID, PID: self.PID}
+			if err := conn.Send(ipc.TypeSubscribe, "", subscribe); err == nil {
+				log.Printf("Connected to dashboard socket, receiving messages for minion %s in %s", self.ID, self.Path)
+				agentTerminal.attach(conn)
+				receiveMinionMessages(conn, agentTerminal, controller, messages, stop)
+				agentTerminal.detach(conn)
+				log.Printf("Dashboard socket connection lost, falling back to message files")
+			}
+			conn.Close()
+		}
+
+		if !pollMinionMessages(stateManager, self, messages, stop) {
+			return
+		}
+	}
+}
+
+// receiveMinionMessages forwards pushed messages, terminal input, resizes and control
+// actions until the connection drops or we are stopped
+func receiveMinionMessages(conn *ipc.Conn, agentTerminal *minionTerminal, controller *minionController, messages chan<- *state.MinionMessage, stop <-chan bool) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close() // Unblocks Receive
+		case <-done:
+		}
+	}()
+
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		if msg.Type == ipc.TypeTerminalInput {
+			var payload ipc.TerminalPayload
+			if err := msg.Decode(&payload); err != nil {
+				log.Printf("Skipping malformed terminal input: %v", err)
+				continue
+			}
+			if err := agentTerminal.writeInput(payload.Data); err != nil {
+				log.Printf("%v", err)
+			}
+			continue
+		}
+		if msg.Type == ipc.TypeTerminalResize {
+			var payload ipc.TerminalSizePayload
+			if err := msg.Decode(&payload); err != nil {
+				log.Printf("Skipping malformed terminal resize: %v", err)
+				continue
+			}
+			agentTerminal.resize(payload.Cols, payload.Rows)
+			continue
+		}
+		if msg.Type == ipc.TypeMinionControl {
+			var payload ipc.ControlPayload
+			if err := msg.Decode(&payload); err != nil {
+				log.Printf("Skipping malformed control message: %v", err)
+				continue
+			}
+
+			log.Printf("Received %s control action", payload.Action)
+			ack := ipc.ControlAckPayload{Action: payload.Action, OK: true}
+			if err := controller.handle(payload.Action); err != nil {
+				ack.OK = false
+				ack.Error = err.Error()
+			}
+			if err := conn.Send(ipc.TypeControlAck, msg.ID, ack); err != nil {
+				log.Printf("Failed to acknowledge %s: %v", payload.Action, err)
+			}
+			continue
+		}
+		if msg.Type != ipc.TypeMinionMessage {
+			continue
+		}
+
+		var message state.MinionMessage
+		if err := msg.Decode(&message); err != nil {
+			log.Printf("Skipping malformed minion message: %v", err)
+			continue
+		}
+
+		select {
+		case messages <- &message:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// minionIdentity returns the ID this minion registers under: the one it had before
+// a restart, or a new one. Agents inherit the variable too, so a minion started from
+// inside an agent, which also has minionPIDEnv, gets an ID of its own.
+func minionIdentity() string {
+	if id := os.Getenv(state.MinionIDEnv); id != "" && os.Getenv(minionPIDEnv) == "" {
+		return id
+	}
+	id := state.NewMinionID()
+	os.Setenv(state.MinionIDEnv, id)
+	return id
+}
+
+// reportMinionMessage records whether a message reached the agent and tells the
+// server, so the sender sees the outcome and the next message can go out
+func reportMinionMessage(stateManager *state.Manager, agentTerminal *minionTerminal, message *state.MinionMessage, deliveryErr error) {
+	completed, err := stateManager.CompleteMinionMessage(message.Path, message.ID, deliveryErr)
+	if err != nil {
+		log.Printf("Failed to record outcome of message %s: %v", message.ID, err)
+		return
+	}
+	agentTerminal.notify(ipc.TypeMessageReceipt, ipc.MessageReceiptPayload{ID: completed.ID, Path: completed.Path, Status: completed.Status})
+}
+
+// pollMinionMessages reads the message files until it is time to retry the socket.
+// It returns false once the minion is stopping.
+func pollMinionMessages(stateManager *state.Manager, self state.Minion, messages chan<- *state.MinionMessage, stop <-chan bool) bool {
+	ticker := time.NewTicker(minionPollInterval)
+	defer ticker.Stop()
+	redial := time.After(minionRedialInterval)
+
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-redial:
+			return true
+		case <-ticker.C:
+			message, err := stateManager.ClaimMinionMessage(self)
+			if err != nil {
+				log.Printf("Error checking minion messages: %v", err)
+				continue
+			}
+			if message == nil {
+				continue
+			}
+
+			select {
+			case messages <- message:
+			case <-stop:
+				return false
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"coding-agent-dashboard/internal/ipc"
+)
+
+// How long a terminated agent gets to exit before it is killed
+const minionTerminateGrace = 10 * time.Second
+
+// errMinionRestart is returned by handleMinionMode once the agent has stopped for a restart
+var errMinionRestart = errors.New("minion restart requested")
+
+// minionController carries out control actions sent from the dashboard
+type minionController struct {
+	cmd           *exec.Cmd
+	agentTerminal *minionTerminal
+	hasPTY        bool
+	exited        chan struct{} // Closed once the agent has exited
+	restart       bool
+	mutex         sync.Mutex
+}
+
+func newMinionController(cmd *exec.Cmd, agentTerminal *minionTerminal, hasPTY bool) *minionController {
+	return &minionController{
+		cmd:           cmd,
+		agentTerminal: agentTerminal,
+		hasPTY:        hasPTY,
+		exited:        make(chan struct{}),
+	}
+}
+
+// handle carries out a control action
+func (c *minionController) handle(action string) error {
+	select {
+	case <-c.exited:
+		return fmt.Errorf("agent has already exited")
+	default:
+	}
+
+	switch action {
+	case ipc.ControlEscape:
+		return c.agentTerminal.writeInput([]byte{0x1b})
+	case ipc.ControlInterrupt:
+		// Claude reads Ctrl-C as a key in its raw-mode TUI rather than as a signal
+		if c.hasPTY {
+			return c.agentTerminal.writeInput([]byte{0x03})
+		}
+		return signalAgent(c.cmd, os.Interrupt)
+	case ipc.ControlTerminate:
+		return c.terminate()
+	case ipc.ControlKill:
+		return signalAgent(c.cmd, os.Kill)
+	case ipc.ControlRestart:
+		c.mutex.Lock()
+		c.restart = true
+		c.mutex.Unlock()
+		return c.terminate()
+	default:
+		return fmt.Errorf("unknown control action %q", action)
+	}
+}
+
+// terminate asks the agent to exit, killing it if it is still running after the grace period
+func (c *minionController) terminate() error {
+	if err := signalAgent(c.cmd, syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-c.exited:
+		case <-time.After(minionTerminateGrace):
+			log.Printf("Agent did not exit within %v, killing it", minionTerminateGrace)
+			if err := signalAgent(c.cmd, os.Kill); err != nil {
+				log.Printf("Failed to kill agent: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// restartRequested reports whether the agent was stopped to be restarted
+func (c *minionController) restartRequested() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.restart
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+	"coding-agent-dashboard/internal/terminal"
+)
+
+// Pause between the end of a paste and the submit, so the agent has taken in
+// the paste before Enter arrives
+const pasteSubmitDelay = 50 * time.Millisecond
+
+// agentType names the agent a minion runs after its command, for per-agent settings
+func agentType(command string) string {
+	name := strings.ToLower(filepath.Base(command))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// injectMessage types a message into the agent and submits it once. Multi-line
+// messages are pasted when the settings allow it, since typing their newlines
+// would submit each line on its own.
+func injectMessage(w io.Writer, message string, injection config.InjectionSettings, pasteEnabled bool) error {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+
+	multiline := strings.Contains(message, "\n")
+	paste := multiline && (injection.Mode == config.InjectPaste || (injection.Mode == config.InjectAuto && pasteEnabled))
+
+	if paste {
+		if _, err := w.Write(terminal.BracketedPaste([]byte(message))); err != nil {
+			return err
+		}
+		time.Sleep(pasteSubmitDelay)
+	} else {
+		// Typed a character at a time so the agent sees it as keyboard input
+		keyDelay := time.Duration(injection.KeyDelayMs) * time.Millisecond
+		for _, char := range message {
+			key := string(char)
+			if char == '\n' {
+				key = injection.Newline
+			}
+			if _, err := io.WriteString(w, key); err != nil {
+				return err
+			}
+			time.Sleep(keyDelay)
+		}
+	}
+
+	if _, err := io.WriteString(w, injection.Submit); err != nil {
+		return err
+	}
+	// Try to flush if it's a File
+	if f, ok := w.(*os.File); ok {
+		f.Sync()
+	}
+	return nil
+}
+
+// injectionSettings loads the injection settings for the agent a minion runs,
+// falling back to the defaults if the settings file can't be read
+func injectionSettings(configDir, command string) config.InjectionSettings {
+	settings, err := config.LoadSettings(configDir)
+	if err != nil {
+		log.Printf("Failed to load settings, using default message injection: %v", err)
+		settings = config.DefaultSettings()
+	}
+	return settings.InjectionFor(agentType(command))
+}
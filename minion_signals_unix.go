@@ -0,0 +1,87 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// Signals sent to the minion that are meant for the agent it wraps
+var forwardedSignals = []os.Signal{
+	syscall.SIGINT,
+	syscall.SIGTERM,
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+	syscall.SIGTSTP,
+	syscall.SIGCONT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+}
+
+// isolateProcessGroup starts a piped command in its own process group, so a Ctrl-C
+// in the terminal reaches it once, through forwardSignals, rather than twice. PTY
+// mode needs nothing: pty.Start already makes the agent a session leader.
+func isolateProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// forwardSignals relays signals sent to the minion to the agent's process group and
+// follows the local terminal's size, until stop is closed
+func forwardSignals(cmd *exec.Cmd, agentTerminal *minionTerminal, stdinIsTerminal bool, stop <-chan bool) {
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, append(forwardedSignals, syscall.SIGWINCH)...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case sig := <-signals:
+			if sig == syscall.SIGWINCH {
+				if !stdinIsTerminal {
+					continue
+				}
+				ws, err := pty.GetsizeFull(os.Stdin)
+				if err != nil {
+					log.Printf("Failed to read terminal size: %v", err)
+					continue
+				}
+				agentTerminal.resize(int(ws.Cols), int(ws.Rows))
+				continue
+			}
+
+			log.Printf("Forwarding %v to process %d", sig, cmd.Process.Pid)
+			if err := signalAgent(cmd, sig); err != nil {
+				log.Printf("Failed to forward %v: %v", sig, err)
+			}
+		}
+	}
+}
+
+// signalAgent sends a signal to the agent's whole process group, as a terminal would
+func signalAgent(cmd *exec.Cmd, sig os.Signal) error {
+	if err := syscall.Kill(-cmd.Process.Pid, sig.(syscall.Signal)); err != nil {
+		return fmt.Errorf("failed to send %v to agent: %w", sig, err)
+	}
+	return nil
+}
+
+// restartMinion replaces this process with a fresh minion running the same command
+// line, so the terminal, PID and working directory carry over
+func restartMinion() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find minion executable: %w", err)
+	}
+	if err := syscall.Exec(executable, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("failed to restart minion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolateProcessGroup is a no-op: Windows has no process groups to isolate
+func isolateProcessGroup(cmd *exec.Cmd) {}
+
+// forwardSignals is a no-op: Windows consoles deliver Ctrl-C to the agent directly
+// and have no resize signal to follow
+func forwardSignals(cmd *exec.Cmd, agentTerminal *minionTerminal, stdinIsTerminal bool, stop <-chan bool) {
+	<-stop
+}
+
+// signalAgent can only kill on Windows, so termination is immediate
+func signalAgent(cmd *exec.Cmd, sig os.Signal) error {
+	if sig != os.Kill && sig != syscall.SIGTERM {
+		return fmt.Errorf("%v is not supported on Windows", sig)
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to kill agent: %w", err)
+	}
+	return nil
+}
+
+// restartMinion runs a fresh minion with the same command line and exits with its
+// exit code, since Windows can't replace the running process
+func restartMinion() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find minion executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restart minion: %w", err)
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/creack/pty"
+
+	"coding-agent-dashboard/internal/ipc"
+	"coding-agent-dashboard/internal/terminal"
+)
+
+// How many messages, mostly chunks of terminal output, may wait for the dashboard
+// before a connection that isn't keeping up is dropped
+const serverOutboxSize = 256
+
+// minionTerminal tees the agent's terminal output to the dashboard server and
+// writes keystrokes typed in the browser back to the agent
+type minionTerminal struct {
+	scrollback *terminal.RingBuffer
+	input      io.Writer          // The agent's stdin: the PTY master, or a pipe when stdin isn't a terminal
+	ptyMaster  *os.File           // The agent's PTY, nil in piped mode where there is no size to follow
+	server     *ipc.Conn          // Connection output is streamed to, nil while the server is unreachable
+	outbox     chan serverMessage // Messages waiting to be sent to server, in order
+	recorder   *terminal.Recorder // Asciicast recording of the session, if enabled
+	pasteMode  terminal.PasteMode // Whether the agent has turned on bracketed paste
+	mutex      sync.Mutex
+	inputMutex sync.Mutex // Held for each write to input, so writers never interleave
+}
+
+// serverMessage is a message queued for the dashboard server
+type serverMessage struct {
+	msgType string
+	payload interface{}
+}
+
+func newMinionTerminal() *minionTerminal {
+	return &minionTerminal{scrollback: terminal.NewRingBuffer(terminal.DefaultScrollback)}
+}
+
+// Write records agent output and queues it for the server when connected. It never
+// waits on the server, since the agent blocks while its output isn't read.
+func (t *minionTerminal) Write(p []byte) (int, error) {
+	// Held across queueing so output stays in order with the scrollback replayed by attach
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.scrollback.Write(p)
+	t.pasteMode.Write(p)
+	if t.recorder != nil {
+		t.recorder.Write(p)
+	}
+	// The copy's buffer is reused once Write returns
+	t.queue(ipc.TypeTerminalOutput, ipc.TerminalPayload{Data: append([]byte(nil), p...)})
+	return len(p), nil
+}
+
+// attach starts streaming to a server connection, replaying the scrollback first
+func (t *minionTerminal) attach(conn *ipc.Conn) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.server = conn
+	t.outbox = make(chan serverMessage, serverOutboxSize)
+	go streamToServer(conn, t.outbox)
+
+	t.queue(ipc.TypeTerminalOutput, ipc.TerminalPayload{Data: t.scrollback.Bytes(), Replay: true})
+}
+
+// notify sends a message to the server if it is connected. The state files hold the
+// truth either way; this only saves the server from finding out late.
+func (t *minionTerminal) notify(msgType string, payload interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.queue(msgType, payload)
+}
+
+// queue hands a message to the connection's sender without waiting. A server that
+// has fallen this far behind is disconnected; reconnecting replays the scrollback,
+// so its terminal view catches up. The mutex must be held.
+func (t *minionTerminal) queue(msgType string, payload interface{}) {
+	if t.server == nil {
+		return
+	}
+
+	select {
+	case t.outbox <- serverMessage{msgType: msgType, payload: payload}:
+	default:
+		log.Printf("Dashboard is not keeping up with terminal output, disconnecting")
+		t.server.Close() // Also ends the receive loop, which reconnects
+		t.disconnect()
+	}
+}
+
+// detach stops streaming to a connection that has gone away
+func (t *minionTerminal) detach(conn *ipc.Conn) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.server == conn {
+		t.disconnect()
+	}
+}
+
+// disconnect forgets the current connection and stops its sender. The mutex must be held.
+func (t *minionTerminal) disconnect() {
+	close(t.outbox)
+	t.server = nil
+	t.outbox = nil
+}
+
+// streamToServer sends queued messages over a connection until the queue is closed
+func streamToServer(conn *ipc.Conn, outbox <-chan serverMessage) {
+	for message := range outbox {
+		if err := conn.Send(message.msgType, "", message.payload); err != nil {
+			log.Printf("Failed to stream to dashboard: %v", err)
+			conn.Close() // The receive loop notices, detaches and reconnects
+			break
+		}
+	}
+
+	// Whatever is still queued would go to a dead connection
+	for range outbox {
+	}
+}
+
+// writeInput passes keystrokes from the dashboard or the local terminal to the agent
+func (t *minionTerminal) writeInput(data []byte) error {
+	return t.withInput(func(input io.Writer) error {
+		if _, err := input.Write(data); err != nil {
+			return fmt.Errorf("failed to write terminal input: %w", err)
+		}
+		return nil
+	})
+}
+
+// withInput runs write with sole use of the agent's input. Injected messages, the
+// browser and the local terminal all write through it, so an escape sequence or
+// a paste is never split by keystrokes from elsewhere.
+func (t *minionTerminal) withInput(write func(input io.Writer) error) error {
+	t.inputMutex.Lock()
+	defer t.inputMutex.Unlock()
+
+	if t.input == nil {
+		return fmt.Errorf("agent has no input to write to")
+	}
+	return write(t.input)
+}
+
+// agentInput is the agent's input as an io.Writer, for copying stdin into
+type agentInput struct {
+	terminal *minionTerminal
+}
+
+func (a agentInput) Write(p []byte) (int, error) {
+	if err := a.terminal.writeInput(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// resize sets the agent's PTY size, from either the local terminal or a browser
+// viewer; whichever resized last wins
+func (t *minionTerminal) resize(cols, rows int) {
+	if cols <= 0 || rows <= 0 || cols > 0xffff || rows > 0xffff {
+		log.Printf("Ignoring invalid terminal size %dx%d", cols, rows)
+		return
+	}
+
+	if t.ptyMaster == nil {
+		return
+	}
+	if err := pty.Setsize(t.ptyMaster, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}); err != nil {
+		log.Printf("Failed to resize terminal: %v", err)
+		return
+	}
+	if t.recorder != nil {
+		t.recorder.Resize(cols, rows)
+	}
+}
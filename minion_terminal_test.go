@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coding-agent-dashboard/internal/config"
+	"coding-agent-dashboard/internal/ipc"
+)
+
+// dialTestServer connects a client to a socket whose accepted end is returned
+func dialTestServer(t *testing.T) (*ipc.Conn, net.Conn) {
+	t.Helper()
+
+	// Unix socket paths are short, so stay clear of the long test temp directory
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "sock"))
+	if err != nil {
+		t.Skipf("unix sockets are not available: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+		close(accepted)
+	}()
+
+	client, err := ipc.Dial(filepath.Join(dir, "sock"))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatal("failed to accept connection")
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestMinionTerminalStreamsInOrder(t *testing.T) {
+	client, server := dialTestServer(t)
+
+	agentTerminal := newMinionTerminal()
+	agentTerminal.Write([]byte("before "))
+	agentTerminal.attach(client)
+	agentTerminal.Write([]byte("one "))
+	agentTerminal.Write([]byte("two"))
+
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i, want := range []struct {
+		data   string
+		replay bool
+	}{{"before ", true}, {"one ", false}, {"two", false}} {
+		msg, err := ipc.ReadMessage(server)
+		if err != nil {
+			t.Fatalf("failed to read message %d: %v", i, err)
+		}
+		var payload ipc.TerminalPayload
+		if err := msg.Decode(&payload); err != nil {
+			t.Fatalf("failed to decode message %d: %v", i, err)
+		}
+		if string(payload.Data) != want.data || payload.Replay != want.replay {
+			t.Errorf("message %d = %q (replay %v), want %q (replay %v)", i, payload.Data, payload.Replay, want.data, want.replay)
+		}
+	}
+}
+
+func TestMinionTerminalDoesNotBlockOnStalledServer(t *testing.T) {
+	// The server end is never read, so the socket fills up
+	client, _ := dialTestServer(t)
+
+	agentTerminal := newMinionTerminal()
+	agentTerminal.attach(client)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		chunk := bytes.Repeat([]byte("x"), 32*1024)
+		for i := 0; i < 2*serverOutboxSize; i++ {
+			agentTerminal.Write(chunk)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("agent output blocked on a stalled dashboard connection")
+	}
+
+	agentTerminal.mutex.Lock()
+	disconnected := agentTerminal.server == nil
+	agentTerminal.mutex.Unlock()
+	if !disconnected {
+		t.Error("stalled dashboard connection was not dropped")
+	}
+
+	// Output is still kept for the next connection's replay
+	if agentTerminal.scrollback.Bytes()[0] != 'x' {
+		t.Error("output written while stalled is missing from the scrollback")
+	}
+
+	// A later attach starts afresh
+	next, _ := dialTestServer(t)
+	agentTerminal.attach(next)
+	agentTerminal.detach(next)
+}
+
+// lockedBuffer records what is written to the agent's input
+type lockedBuffer struct {
+	buffer bytes.Buffer
+	mutex  sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func TestMinionTerminalInjectionIsNotInterleaved(t *testing.T) {
+	input := &lockedBuffer{}
+	agentTerminal := newMinionTerminal()
+	agentTerminal.input = input
+
+	injection := config.DefaultInjection
+	injection.KeyDelayMs = 1
+
+	injected := make(chan struct{})
+	go func() {
+		defer close(injected)
+		err := agentTerminal.withInput(func(w io.Writer) error {
+			return injectMessage(w, "hello world", injection, false)
+		})
+		if err != nil {
+			t.Errorf("injectMessage() = %v", err)
+		}
+	}()
+
+	// Keystrokes from the browser and the local terminal while the message is typed
+	stdin := agentInput{agentTerminal}
+	keystrokes := 0
+	for typing := true; typing; keystrokes++ {
+		select {
+		case <-injected:
+			typing = false
+		default:
+		}
+		agentTerminal.writeInput([]byte{0x1b})
+		stdin.Write([]byte("k"))
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	got := input.buffer.String()
+	if !strings.Contains(got, "hello world\r") {
+		t.Errorf("injected message was interleaved with other input: %q", got)
+	}
+	if strings.Count(got, "k") != keystrokes || strings.Count(got, "\x1b") != keystrokes {
+		t.Errorf("keystrokes were lost: %q", got)
+	}
+}
+
+func TestMinionTerminalInputWithoutAgent(t *testing.T) {
+	agentTerminal := newMinionTerminal()
+	if err := agentTerminal.writeInput([]byte("x")); err == nil {
+		t.Error("writeInput() without an agent input succeeded")
+	}
+}
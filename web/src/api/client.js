@@ -56,6 +56,28 @@ class ApiClient {
     })
   }
 
+  async getWorktrees(repoId) {
+    return this.request(`/repositories/${repoId}/worktrees`)
+  }
+
+  // options: branch, base (ref a new branch starts from), path
+  async createWorktree(repoId, options) {
+    return this.request(`/repositories/${repoId}/worktrees`, {
+      method: 'POST',
+      body: JSON.stringify(options)
+    })
+  }
+
+  // options: force, deleteBranch
+  async removeWorktree(repoId, path, options = {}) {
+    const params = new URLSearchParams({ path })
+    if (options.force) params.set('force', 'true')
+    if (options.deleteBranch) params.set('delete_branch', 'true')
+    return this.request(`/repositories/${repoId}/worktrees?${params}`, {
+      method: 'DELETE'
+    })
+  }
+
   // Status endpoints
   async getStatus() {
     return this.request('/status')
@@ -79,13 +101,151 @@ class ApiClient {
     return this.request(`/hooks/status?path=${encodeURIComponent(path)}`)
   }
 
-  async installHook(path) {
+  async installHook(path, approval = false) {
     return this.request('/hooks/install', {
       method: 'POST',
-      body: JSON.stringify({ path })
+      body: JSON.stringify({ path, approval })
+    })
+  }
+
+  // Tool approvals
+  async getApprovals() {
+    return this.request('/approvals')
+  }
+
+  async decideApproval(id, decision, reason = '') {
+    return this.request(`/approvals/${id}`, {
+      method: 'POST',
+      body: JSON.stringify({ decision, reason })
+    })
+  }
+
+  // Tool policies
+  async getPolicy(repositoryId = '') {
+    const query = repositoryId ? `?repository=${encodeURIComponent(repositoryId)}` : ''
+    return this.request(`/policy${query}`)
+  }
+
+  async savePolicy(policy, repositoryId = '') {
+    const query = repositoryId ? `?repository=${encodeURIComponent(repositoryId)}` : ''
+    return this.request(`/policy${query}`, {
+      method: 'PUT',
+      body: JSON.stringify(policy)
+    })
+  }
+
+  async getPolicyDecisions(path = '') {
+    const query = path ? `?path=${encodeURIComponent(path)}` : ''
+    return this.request(`/policy/decisions${query}`)
+  }
+
+  async getStatusHistory(path = '', since = '') {
+    const params = new URLSearchParams()
+    if (path) params.set('path', path)
+    if (since) params.set('since', since)
+    const query = params.toString() ? `?${params}` : ''
+    return this.request(`/status/history${query}`)
+  }
+
+  // mode is head (uncommitted changes), merge-base (the whole branch) or commit;
+  // options: base (branch for merge-base), commit
+  async getDiff(path, mode = 'head', options = {}) {
+    const params = new URLSearchParams({ path, mode })
+    if (options.base) params.set('base', options.base)
+    if (options.commit) params.set('commit', options.commit)
+    return this.request(`/diff?${params}`)
+  }
+
+  // Running minions, oldest first
+  async getMinions(path = '') {
+    const query = path ? `?path=${encodeURIComponent(path)}` : ''
+    return this.request(`/minions${query}`)
+  }
+
+  // options: command (array), prompt, model, env (object), record
+  async launchMinion(path, options = {}) {
+    return this.request('/minion/launch', {
+      method: 'POST',
+      body: JSON.stringify({ path, ...options })
+    })
+  }
+
+  // Create a worktree for a branch, install hooks and launch the agent with a prompt.
+  // task: repository_id, branch, prompt, and optionally base, command, model, env, record, approval
+  async createTask(task) {
+    return this.request('/tasks', {
+      method: 'POST',
+      body: JSON.stringify(task)
+    })
+  }
+
+  // action is escape, interrupt, terminate, kill or restart
+  async controlMinion(path, action) {
+    return this.request('/minion/control', {
+      method: 'POST',
+      body: JSON.stringify({ path, action })
+    })
+  }
+
+  // Messages in every delivery state, newest first
+  async getMinionMessages(path = '') {
+    const query = path ? `?path=${encodeURIComponent(path)}` : ''
+    return this.request(`/minion/messages${query}`)
+  }
+
+  async retryMinionMessage(id) {
+    return this.request(`/minion/messages/${encodeURIComponent(id)}/retry`, {
+      method: 'POST'
     })
   }
 
+  async sendMinionMessageNow(id) {
+    return this.request(`/minion/messages/${encodeURIComponent(id)}/send-now`, {
+      method: 'POST'
+    })
+  }
+
+  // Scheduled and recurring minion messages, oldest first
+  async getSchedules(path = '') {
+    const query = path ? `?path=${encodeURIComponent(path)}` : ''
+    return this.request(`/schedules${query}`)
+  }
+
+  // schedule: path, minion_id, message, kind (at, idle or cron), at, idle_minutes, cron, ttl_seconds
+  async createSchedule(schedule) {
+    return this.request('/schedules', {
+      method: 'POST',
+      body: JSON.stringify(schedule)
+    })
+  }
+
+  async setScheduleEnabled(id, enabled) {
+    return this.request(`/schedules/${encodeURIComponent(id)}`, {
+      method: 'POST',
+      body: JSON.stringify({ enabled })
+    })
+  }
+
+  async deleteSchedule(id) {
+    return this.request(`/schedules/${encodeURIComponent(id)}`, {
+      method: 'DELETE'
+    })
+  }
+
+  minionTerminalURL(path) {
+    const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
+    return `${protocol}//${window.location.host}${this.baseURL}/minion/terminal?path=${encodeURIComponent(path)}`
+  }
+
+  async getRecordings(path = '') {
+    const query = path ? `?path=${encodeURIComponent(path)}` : ''
+    return this.request(`/recordings${query}`)
+  }
+
+  recordingURL(id) {
+    return `${this.baseURL}/recordings/${encodeURIComponent(id)}`
+  }
+
   // Webhook endpoint (for Claude integration)
   async sendWebhook(data) {
     return this.request('/webhook/claude', {